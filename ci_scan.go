@@ -14,13 +14,20 @@ import (
 
 func ciScanCmd() *cobra.Command {
 	var file string
+	var useGraphQL bool
 	cmd := cobra.Command{
 		Use: "scan-ci [repo]",
 		Run: func(cmd *cobra.Command, args []string) {
-			walkReposForCI(append(args, loadRepos(file)...))
+			repos := append(args, loadRepos(file)...)
+			if useGraphQL {
+				walkReposForCIGraphQL(repos)
+				return
+			}
+			walkReposForCI(repos)
 		},
 	}
 	cmd.Flags().StringVar(&file, "file", "", "a file of new line delimited repos to get")
+	cmd.Flags().BoolVar(&useGraphQL, "graphql", false, "scan the repos in batches with graphql to use fewer requests")
 	return &cmd
 }
 
@@ -53,16 +60,7 @@ func queryRepoForCI(repo repo) repoStatus {
 		ghaFiles := make([]fileEntry, 0)
 		panicOnErr(json.Unmarshal(raw, &ghaFiles))
 		for _, file := range ghaFiles {
-			switch file.Name {
-			case "fossa.yml":
-				state.Fossa = true
-			case "stalebot.yml":
-				state.Stalebot = true
-			case "renovate.yml":
-				state.Renovate = true
-			default:
-				state.Actions = append(state.Actions, file.Name)
-			}
+			state.addWorkflow(file.Name)
 		}
 	}
 	return state
@@ -99,6 +97,19 @@ type repoStatus struct {
 	Actions     []string
 }
 
+func (s *repoStatus) addWorkflow(name string) {
+	switch name {
+	case "fossa.yml":
+		s.Fossa = true
+	case "stalebot.yml":
+		s.Stalebot = true
+	case "renovate.yml":
+		s.Renovate = true
+	default:
+		s.Actions = append(s.Actions, name)
+	}
+}
+
 func (s repoStatus) Fields() []csvField {
 	return []csvField{
 		{"name", s.Name},
@@ -119,10 +130,14 @@ func (s repoStatus) Fields() []csvField {
 }
 
 func cleanCodeowners(e fileEntry) []string {
+	return parseCodeowners(e.Contents())
+}
+
+func parseCodeowners(contents []byte) []string {
 	owners := map[string]struct{}{}
 	curOwner := []byte{}
 
-	for _, b := range e.Contents() {
+	for _, b := range contents {
 		if b == '@' {
 			curOwner = append(curOwner, b)
 			continue
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// how many repos go into a single graphql query
+const ciScanBatchSize = 50
+
+// the files that queryRepoForCI looks for, fetched from the default branch of each repo
+const ciScanFragment = `
+fragment ciFiles on Repository {
+	jenkinsfile: object(expression: "HEAD:Jenkinsfile") { __typename }
+	circleci: object(expression: "HEAD:.circleci/config.yml") { __typename }
+	toml: object(expression: "HEAD:netlify.toml") { __typename }
+	security: object(expression: "HEAD:.github/SECURITY.MD") { __typename }
+	codeowners: object(expression: "HEAD:.github/CODEOWNERS") { ... on Blob { text } }
+	workflows: object(expression: "HEAD:.github/workflows") { ... on Tree { entries { name } } }
+}
+`
+
+type ciScanObject struct {
+	Typename string `json:"__typename"`
+}
+
+type ciScanResult struct {
+	Jenkinsfile *ciScanObject
+	CircleCI    *ciScanObject
+	TOML        *ciScanObject
+	Security    *ciScanObject
+	Codeowners  *struct {
+		Text string
+	}
+	Workflows *struct {
+		Entries []struct {
+			Name string
+		}
+	}
+}
+
+// ciScanCost is the running total of graphql points the scan used
+var ciScanCost int
+
+func walkReposForCIGraphQL(repos []string) {
+	batch := []repo{}
+	for i, r := range repos {
+		batch = append(batch, repo{Name: qualifyRepo(r)})
+		if len(batch) == ciScanBatchSize || i == len(repos)-1 {
+			log.Info("starting query for a batch of repos",
+				zap.Int("index", i),
+				zap.Int("total", len(repos)),
+			)
+			scanBatchForCI(batch)
+			batch = []repo{}
+		}
+	}
+	log.Info("finished the graphql scan", zap.Int("cost", ciScanCost))
+}
+
+func searchReposAndScanGraphQL() {
+	batch := []repo{}
+	readRepoPages(func(r repo) error {
+		batch = append(batch, r)
+		if len(batch) == ciScanBatchSize {
+			scanBatchForCI(batch)
+			batch = []repo{}
+		}
+		return nil
+	})
+	if len(batch) > 0 {
+		scanBatchForCI(batch)
+	}
+	log.Info("finished the graphql scan", zap.Int("cost", ciScanCost))
+}
+
+// scanBatchForCI fetches the CI state of every repo in the batch with one query and
+// encodes a repoStatus for each, the same as queryRepoForCI would
+func scanBatchForCI(batch []repo) {
+	var query strings.Builder
+	query.WriteString("query {\n\trateLimit { cost remaining }\n")
+	for i, r := range batch {
+		parts := strings.SplitN(r.Name, "/", 2)
+		if len(parts) != 2 {
+			panic(fmt.Sprintf("expected the repo to be owner/name: %s", r.Name))
+		}
+		owner, err := json.Marshal(parts[0])
+		panicOnErr(err)
+		name, err := json.Marshal(parts[1])
+		panicOnErr(err)
+		fmt.Fprintf(&query, "\tr%d: repository(owner: %s, name: %s) { ...ciFiles }\n", i, owner, name)
+	}
+	query.WriteString("}\n")
+	query.WriteString(ciScanFragment)
+
+	data := map[string]json.RawMessage{}
+	for _, e := range queryGraphQLPartial(query.String(), nil, &data) {
+		log.Warn("failed to scan part of the batch", zap.String("error", e.Message), zap.Any("path", e.Path))
+	}
+
+	rate := struct {
+		Cost      int
+		Remaining int
+	}{}
+	if raw, ok := data["rateLimit"]; ok {
+		panicOnErr(json.Unmarshal(raw, &rate))
+	}
+	ciScanCost += rate.Cost
+	log.Debug("scanned a batch of repos",
+		zap.Int("repos", len(batch)),
+		zap.Int("cost", rate.Cost),
+		zap.Int("remaining", rate.Remaining),
+	)
+
+	for i, r := range batch {
+		state := repoStatus{repo: r}
+		raw, ok := data[fmt.Sprintf("r%d", i)]
+		if !ok || string(raw) == "null" {
+			log.Warn("no results for repo", zap.String("repo", r.Name))
+			panicOnErr(enc(state))
+			continue
+		}
+
+		var res ciScanResult
+		panicOnErr(json.Unmarshal(raw, &res))
+		state.Jenkinsfile = res.Jenkinsfile != nil
+		state.CircleCI = res.CircleCI != nil
+		state.RootTOML = res.TOML != nil
+		state.Security = res.Security != nil
+		if res.Codeowners != nil {
+			state.CodeOwners = parseCodeowners([]byte(res.Codeowners.Text))
+		}
+		if res.Workflows != nil {
+			for _, file := range res.Workflows.Entries {
+				state.addWorkflow(file.Name)
+			}
+		}
+		panicOnErr(enc(state))
+	}
+}
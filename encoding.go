@@ -1,59 +1,308 @@
 package main
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
-	"sync"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
 )
 
 type encoder func(obj interface{}) error
 
-func buildJSONEncoder(out io.WriteCloser) encoder {
-	if pretty {
-		return func(obj interface{}) error {
-			bs, err := json.MarshalIndent(obj, "", "  ")
-			if err != nil {
-				return err
-			}
-			_, err = out.Write(append(bs, '\n'))
-			return err
+// finisher writes anything the format needs after the last object, it's called
+// before the output is closed
+type finisher func() error
+
+const (
+	formatNDJSON = "ndjson"
+	formatJSON   = "json"
+	formatYAML   = "yaml"
+	formatCSV    = "csv"
+	formatTSV    = "tsv"
+)
+
+// the csv options, columns picks and orders the fields by their header
+var csvColumns []string
+var csvDelimiter string
+
+// buildEncoder makes the encoder for the format, appending is if the output already
+// has data in it so the csv header isn't written again
+func buildEncoder(out io.WriteCloser, format string, appending bool) (encoder, finisher) {
+	if format == "" {
+		// a stream of pretty objects isn't valid json, so pretty implies an array
+		format = formatNDJSON
+		if pretty {
+			format = formatJSON
 		}
 	}
+
+	switch format {
+	case formatNDJSON:
+		return buildNDJSONEncoder(out)
+	case formatJSON:
+		return buildJSONArrayEncoder(out)
+	case formatYAML:
+		return buildYAMLEncoder(out)
+	case formatCSV:
+		return buildCSVEncoder(out, csvComma(','), !appending)
+	case formatTSV:
+		return buildCSVEncoder(out, csvComma('\t'), !appending)
+	case formatMarkdown:
+		return buildMarkdownEncoder(out)
+	case formatHTML:
+		return buildHTMLEncoder(out)
+	}
+	panic(fmt.Sprintf("unexpected output format: %s", format))
+}
+
+func buildNDJSONEncoder(out io.WriteCloser) (encoder, finisher) {
 	writer := json.NewEncoder(out)
 	return func(obj interface{}) error {
 		return writer.Encode(&obj)
-	}
+	}, nil
 }
 
-func buildCSVEncoder(out io.WriteCloser) encoder {
-	writer := csv.NewWriter(out)
-	var once sync.Once
+// buildJSONArrayEncoder streams the objects as the elements of a single array, the
+// closing bracket is written when it's finished
+func buildJSONArrayEncoder(out io.WriteCloser) (encoder, finisher) {
+	var count int
+	write := func(obj interface{}) error {
+		var bs []byte
+		var err error
+		if pretty {
+			bs, err = json.MarshalIndent(obj, "  ", "  ")
+		} else {
+			bs, err = json.Marshal(obj)
+		}
+		if err != nil {
+			return err
+		}
 
+		sep := ",\n  "
+		if count == 0 {
+			sep = "[\n  "
+		}
+		count++
+		_, err = out.Write(append([]byte(sep), bs...))
+		return err
+	}
+	finish := func() error {
+		end := "\n]\n"
+		if count == 0 {
+			end = "[]\n"
+		}
+		_, err := io.WriteString(out, end)
+		return err
+	}
+	return write, finish
+}
+
+// buildYAMLEncoder writes each object as its own yaml document. The objects go
+// through json first so the keys match the json output and keep their order.
+func buildYAMLEncoder(out io.WriteCloser) (encoder, finisher) {
+	var count int
 	return func(obj interface{}) error {
-		encObj, ok := obj.(csvWritable)
-		if !ok {
-			return errors.New("object doesn't implement csvWritable")
+		bs, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bytes.NewReader(bs))
+		dec.UseNumber()
+		val, err := readYAMLValue(dec)
+		if err != nil {
+			return err
+		}
+		doc, err := yaml.Marshal(val)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			doc = append([]byte("---\n"), doc...)
 		}
+		count++
+		_, err = out.Write(doc)
+		return err
+	}, nil
+}
 
-		var headers []string
-		var entries []string
-		for _, f := range encObj.Fields() {
-			headers = append(headers, f.header)
+// readYAMLValue reads the next json value from the decoder, objects become
+// yaml.MapSlice to keep the order of their keys
+func readYAMLValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
 
-			entries = append(entries, fmt.Sprintf("%v", f.value))
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				val, err := readYAMLValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, yaml.MapItem{Key: key, Value: val})
+			}
+			_, err := dec.Token()
+			return obj, err
+		case '[':
+			arr := []interface{}{}
+			for dec.More() {
+				val, err := readYAMLValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, val)
+			}
+			_, err := dec.Token()
+			return arr, err
 		}
-		once.Do(func() {
-			panicOnErr(writer.Write(headers))
-		})
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+	return tok, nil
+}
 
-		panicOnErr(writer.Write(entries))
+func csvComma(def rune) rune {
+	if csvDelimiter == "" {
+		return def
+	}
+	runes := []rune(csvDelimiter)
+	if len(runes) != 1 {
+		panic(fmt.Sprintf("the delimiter must be a single character: %q", csvDelimiter))
+	}
+	return runes[0]
+}
+
+func buildCSVEncoder(out io.WriteCloser, comma rune, header bool) (encoder, finisher) {
+	writer := csv.NewWriter(out)
+	writer.Comma = comma
+
+	return func(obj interface{}) error {
+		rows, err := tableRows(obj)
+		if err != nil {
+			return err
+		}
+		for _, fields := range rows {
+			var headers []string
+			var entries []string
+			for _, f := range fields {
+				headers = append(headers, f.header)
+				entries = append(entries, csvValue(f.value))
+			}
+			if header {
+				panicOnErr(writer.Write(headers))
+				header = false
+			}
+			panicOnErr(writer.Write(entries))
+		}
 
 		writer.Flush()
 		return writer.Error()
+	}, nil
+}
+
+// tableRows is the fields of each row the object is written as by the csv and
+// table formats, limited to the --columns if there are any
+func tableRows(obj interface{}) ([][]csvField, error) {
+	if multi, ok := obj.(csvRowsWritable); ok {
+		if rows := multi.Rows(); len(rows) > 0 {
+			out := [][]csvField{}
+			for _, row := range rows {
+				fields, err := tableRows(row)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, fields...)
+			}
+			return out, nil
+		}
+	}
+
+	var fields []csvField
+	if encObj, ok := obj.(csvWritable); ok {
+		fields = encObj.Fields()
+	} else {
+		var err error
+		if fields, err = genericFields(obj); err != nil {
+			return nil, err
+		}
 	}
+	if len(csvColumns) > 0 {
+		fields = pickColumns(fields, csvColumns)
+	}
+	return [][]csvField{fields}, nil
+}
+
+// genericFields is for objects without their own fields, like the responses from
+// query-github. Objects get a column per key in sorted order, anything nested is
+// written as json.
+func genericFields(obj interface{}) ([]csvField, error) {
+	generic, err := toGeneric(obj)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := generic.(map[string]interface{})
+	if !ok {
+		return []csvField{{"value", generic}}, nil
+	}
+
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := []csvField{}
+	for _, k := range keys {
+		fields = append(fields, csvField{k, m[k]})
+	}
+	return fields, nil
+}
+
+// pickColumns orders the fields like the columns, a column that doesn't match a
+// header is left empty so every row has the same shape
+func pickColumns(fields []csvField, columns []string) []csvField {
+	out := []csvField{}
+	for _, col := range columns {
+		field := csvField{header: col}
+		for _, f := range fields {
+			if strings.EqualFold(f.header, col) {
+				field = f
+				break
+			}
+		}
+		out = append(out, field)
+	}
+	return out
+}
+
+func csvValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		bs, err := json.Marshal(v)
+		panicOnErr(err)
+		return string(bs)
+	}
+	return fmt.Sprintf("%v", val)
 }
 
 type csvField struct {
@@ -64,3 +313,9 @@ type csvField struct {
 type csvWritable interface {
 	Fields() []csvField
 }
+
+// csvRowsWritable is for objects that are written as multiple rows, if there
+// aren't any rows the object is written as a csvWritable
+type csvRowsWritable interface {
+	Rows() []csvWritable
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type graphQLError struct {
+	Type    string
+	Message string
+	Path    []interface{}
+}
+
+// queryGraphQL sends the query to the graphql endpoint and decodes the data of
+// the response into the provided value. Any errors in the response cause a panic.
+func queryGraphQL(query string, vars map[string]interface{}, into interface{}) {
+	if errs := queryGraphQLPartial(query, vars, into); len(errs) > 0 {
+		msgs := []string{}
+		for _, e := range errs {
+			msgs = append(msgs, e.Message)
+		}
+		panic(fmt.Sprintf("graphql query failed: %s", strings.Join(msgs, "; ")))
+	}
+}
+
+// queryGraphQLPartial is for queries where some parts failing is expected, it
+// decodes whatever data came back and returns the errors
+func queryGraphQLPartial(query string, vars map[string]interface{}, into interface{}) []graphQLError {
+	body, err := json.Marshal(&struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables,omitempty"`
+	}{
+		Query:     query,
+		Variables: vars,
+	})
+	panicOnErr(err)
+
+	code, raw := queryGitHub("/graphql",
+		withMethod(http.MethodPost),
+		withPayload(body),
+	)
+	requireCode(http.StatusOK, code, raw)
+
+	rsp := struct {
+		Data   json.RawMessage
+		Errors []graphQLError
+	}{}
+	panicOnErr(json.Unmarshal(raw, &rsp))
+	if into != nil && len(rsp.Data) > 0 && string(rsp.Data) != "null" {
+		panicOnErr(json.Unmarshal(rsp.Data, into))
+	}
+	return rsp.Errors
+}
@@ -5,12 +5,19 @@ import (
 )
 
 func listAndScanCmd() *cobra.Command {
+	var useGraphQL bool
 	cmd := cobra.Command{
 		Use: "list-and-scan",
 		Run: func(cmd *cobra.Command, args []string) {
+			if useGraphQL {
+				searchReposAndScanGraphQL()
+				return
+			}
 			searchReposAndScan()
 		},
 	}
+	cmd.Flags().BoolVar(&useGraphQL, "graphql", false, "scan the repos in batches with graphql to use fewer requests")
+	addRepoFilterFlags(&cmd)
 	return &cmd
 }
 
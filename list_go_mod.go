@@ -15,6 +15,7 @@ func listGoMods() *cobra.Command {
 			searchReposForGoMod()
 		},
 	}
+	addRepoFilterFlags(&cmd)
 	return &cmd
 }
 
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"os"
 
@@ -10,9 +11,11 @@ import (
 
 var ghToken string
 var log *zap.Logger
-var skipArchive, verbose, pretty bool
+var skipArchive, verbose, pretty, useCSV bool
 var limit int
 var enc encoder
+var encFinish finisher
+var format string
 var out io.WriteCloser = os.Stdout
 
 var ghQueries int
@@ -29,21 +32,28 @@ func main() {
 	root.PersistentFlags().StringP("token", "t", "", "the github access token")
 	root.PersistentFlags().BoolVar(&skipArchive, "skip-archived", false, "if we should skip archived repos")
 	root.PersistentFlags().BoolVar(&verbose, "verbose", false, "if we log the paths we query")
-	root.PersistentFlags().BoolVar(&pretty, "pretty", false, "if the json should be pretty")
+	root.PersistentFlags().BoolVar(&pretty, "pretty", false, "if the json should be pretty, implies --format json")
+	root.PersistentFlags().StringVar(&format, "format", "", "the output format: ndjson, json, yaml, csv, tsv, markdown or html, defaults to ndjson")
+	root.PersistentFlags().BoolVar(&useCSV, "csv", false, "if we should encode with csv, the same as --format csv")
+	root.PersistentFlags().StringSliceVar(&csvColumns, "columns", nil, "the csv columns to write and their order, by header name")
+	root.PersistentFlags().StringVar(&csvDelimiter, "delimiter", "", "the csv field delimiter, defaults to a comma or a tab for tsv")
+	root.PersistentFlags().StringVar(&sqlitePath, "sqlite", "", "write the results to tables in this sqlite database instead of the output")
 	root.PersistentFlags().IntVar(&limit, "limit", 0, "a limit on the number of repos to scan")
 	root.PersistentFlags().String("out", "", "an optional file to append to, default is stdout")
+	root.PersistentFlags().StringSliceVar(&selectPaths, "select", nil, "only output these fields, as dotted paths like name,owner.login")
+	root.PersistentFlags().StringArrayVar(&filterExprs, "filter", nil, "only output objects matching this expression, like archived==false or size>100, can be repeated")
 
 	cmds := setPreActions(
 		queryGitHubCmd(),
+		queryGraphQLCmd(),
 
 		projectCmd(),
 
 		transferRepoCmd(),
-
-		supportCSV(ciScanCmd()),
-		supportCSV(listReposCmd()),
-		supportCSV(listAndScanCmd()),
-		supportCSV(listGoMods()),
+		supportSQLite(ciScanCmd()),
+		listReposCmd(),
+		supportSQLite(listAndScanCmd()),
+		supportSQLite(listGoMods()),
 	)
 	root.AddCommand(cmds...)
 
@@ -58,13 +68,24 @@ func setPreActions(commands ...*cobra.Command) []*cobra.Command {
 	}
 
 	postrun := func(cmd *cobra.Command, args []string) {
+		if encFinish != nil {
+			panicOnErr(encFinish())
+		}
 		panicOnErr(out.Close())
 		log.Sugar().Debugf("did %d queries to github", ghQueries)
 	}
 
 	for _, c := range commands {
 		if c.Run != nil {
-			c.PreRun = prerun
+			// keep any setup the command already has
+			setup := c.PreRun
+			c.PreRun = func(cmd *cobra.Command, args []string) {
+				prerun(cmd, args)
+				if setup != nil {
+					setup(cmd, args)
+				}
+				enc = withSelection(enc)
+			}
 			c.PostRun = postrun
 		}
 		if c.HasSubCommands() {
@@ -85,26 +106,34 @@ func setToken(cmd *cobra.Command) {
 }
 
 func setOutput(cmd *cobra.Command) {
+	var appending bool
 	outName, _ := cmd.Flags().GetString("out")
 	if outName != "" {
+		if info, err := os.Stat(outName); err == nil && info.Size() > 0 {
+			appending = true
+		}
 		f, err := os.OpenFile(outName, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
 		panicOnErr(err)
 		out = f
 	}
-	enc = buildJSONEncoder(out)
-}
-
-func supportCSV(cmd *cobra.Command) *cobra.Command {
-	var useCSV bool
-	cmd.Flags().BoolVar(&useCSV, "csv", false, "if we should encode with csv")
-	setup := cmd.PreRun
-	cmd.PreRun = func(cmd *cobra.Command, args []string) {
-		setup(cmd, args)
-		if useCSV {
-			enc = buildCSVEncoder(out)
+	if useCSV {
+		if format != "" && format != formatCSV {
+			panic(fmt.Sprintf("--csv can't be used with --format %s", format))
+		}
+		format = formatCSV
+	}
+	if sqlitePath != "" {
+		// fail before the command does anything, not on the first thing it writes
+		if cmd.Annotations[sqliteAnnotation] == "" {
+			panic(fmt.Sprintf("%s doesn't support --sqlite", cmd.CommandPath()))
+		}
+		if len(selectPaths) > 0 {
+			panic("--select can't be used with --sqlite, the tables have fixed columns")
 		}
+		enc, encFinish = buildSQLiteEncoder(sqlitePath)
+		return
 	}
-	return cmd
+	enc, encFinish = buildEncoder(out, format, appending)
 }
 
 func setVerbosity(cmd *cobra.Command) {
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
@@ -16,6 +17,12 @@ type projectCard struct {
 	ID         int
 	Note       string `json:",omitempty"`
 	ContentURL string `json:"content_url,omitempty"`
+	Archived   bool   `json:"archived,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	History []cardMove `json:",omitempty"`
 }
 
 type projectColumn struct {
@@ -31,20 +38,108 @@ type project struct {
 	Body    string
 	State   string
 	HTMLURL string `json:"html_url"`
+	Repo    string `json:",omitempty"`
 
 	Cols []*projectColumn `json:",omitempty"`
 }
 
+func (p project) Fields() []csvField {
+	return append(p.rowFields(), csvField{"columns", len(p.Cols)})
+}
+
+// rowFields are the fields that start every card row of the project
+func (p project) rowFields() []csvField {
+	return []csvField{
+		{"project id", p.ID},
+		{"project", p.Name},
+		{"state", p.State},
+		{"repo", p.Repo},
+		{"url", p.HTMLURL},
+	}
+}
+
+// Rows writes a row per card, columns without any cards get a row with the card left empty
+func (p project) Rows() []csvWritable {
+	rows := []csvWritable{}
+	for _, col := range p.Cols {
+		rows = append(rows, col.cardRows(&p)...)
+	}
+	return rows
+}
+
+func (c projectColumn) Fields() []csvField {
+	return []csvField{
+		{"column id", c.ID},
+		{"column", c.Name},
+		{"cards", len(c.Cards)},
+	}
+}
+
+func (c projectColumn) Rows() []csvWritable {
+	return c.cardRows(nil)
+}
+
+func (c projectColumn) cardRows(proj *project) []csvWritable {
+	if len(c.Cards) == 0 {
+		return []csvWritable{projectCardRow{project: proj, column: &c}}
+	}
+	rows := []csvWritable{}
+	for i := range c.Cards {
+		rows = append(rows, projectCardRow{project: proj, column: &c, card: &c.Cards[i]})
+	}
+	return rows
+}
+
+// projectCardRow is a card with the column and project it's on, either can be missing
+type projectCardRow struct {
+	project *project
+	column  *projectColumn
+	card    *projectCard
+}
+
+func (r projectCardRow) Fields() []csvField {
+	out := []csvField{}
+	if r.project != nil {
+		out = append(out, r.project.rowFields()...)
+	}
+	out = append(out, csvField{"column id", r.column.ID}, csvField{"column", r.column.Name})
+	if r.card != nil {
+		return append(out, r.card.Fields()...)
+	}
+	for _, f := range (projectCard{}).Fields() {
+		out = append(out, csvField{header: f.header})
+	}
+	return out
+}
+
+func (c projectCard) Fields() []csvField {
+	return []csvField{
+		{"card id", c.ID},
+		{"note", c.Note},
+		{"content url", c.ContentURL},
+		{"archived", c.Archived},
+		{"created at", c.CreatedAt.Format(time.RFC3339)},
+		{"updated at", c.UpdatedAt.Format(time.RFC3339)},
+	}
+}
+
 func projectCmd() *cobra.Command {
 	cmd := cobra.Command{
 		Use: "projects",
 	}
 
 	cmd.AddCommand(
-		listProjectsCmd(),
-		queryProjectCmd(),
+		supportSQLite(listProjectsCmd()),
+		supportSQLite(queryProjectCmd()),
 		emptyProjectCmd(),
 		migrateProjectCmd(),
+		exportProjectCmd(),
+		importProjectCmd(),
+		syncProjectCmd(),
+		analyzeProjectCmd(),
+		migrateProjectV2Cmd(),
+		listProjectsV2Cmd(),
+		queryProjectV2Cmd(),
 	)
 
 	// cmd.AddCommand(projectQLCommand())
@@ -53,17 +148,26 @@ func projectCmd() *cobra.Command {
 }
 
 func listProjectsCmd() *cobra.Command {
-	var projectID, org string
+	var projectID string
+	var owner projectOwner
+	var allRepos bool
 	cmd := cobra.Command{
 		Use: "list",
 		Run: func(cmd *cobra.Command, args []string) {
-			readProjectPages(org, func(p project) error {
+			if allRepos {
+				listAllRepoProjects()
+				return
+			}
+			readProjectPages(owner.listPath(), func(p project) error {
+				p.Repo = owner.repo
 				return enc(&p)
 			})
 		},
 	}
 	cmd.Flags().StringVar(&projectID, "project-id", "", "a specific projectID to gather")
-	cmd.PersistentFlags().StringVar(&org, "org", "netlify", "the organization to create the new project in")
+	addProjectOwnerFlags(&cmd, &owner)
+	cmd.Flags().BoolVar(&allRepos, "all-repos", false, "list the projects of every repo in the org")
+	addRepoFilterFlags(&cmd)
 
 	return &cmd
 }
@@ -81,78 +185,125 @@ func queryProjectCmd() *cobra.Command {
 	return &cmd
 }
 
-func emptyProjectCmd() *cobra.Command {
-	cmd := cobra.Command{
-		Use: "clear [id]",
-		Run: func(cmd *cobra.Command, args []string) {
-			boardID := args[0]
-			proj := queryProject(boardID, false)
-			for _, c := range proj.Cols {
-				log.Debug("removing column", zap.String("name", c.Name), zap.Int("id", c.ID))
-				code, raw := queryGitHub(fmt.Sprintf("/projects/columns/%d", c.ID),
-					withMethod(http.MethodDelete),
-				)
-				requireCode(204, code, raw)
-			}
-		},
-		Args: cobra.ExactArgs(1),
-	}
-	return &cmd
-}
-
 func migrateProjectCmd() *cobra.Command {
 	var useDisk bool
-	var destProjectID string
-	var org string
+	var repoMap string
+	var opts migrateOpts
 	cmd := cobra.Command{
 		Use: "migrate [id]",
 		Run: func(cmd *cobra.Command, args []string) {
-			migrateProject(args[0], destProjectID, org, useDisk)
+			if opts.issueMode != issueModeTransfer && opts.issueMode != issueModeRecreate {
+				panic(fmt.Sprintf("unexpected issue mode: %s", opts.issueMode))
+			}
+			if opts.journal == "" {
+				opts.journal = defaultJournalPath(args[0])
+			}
+			if repoMap != "" {
+				opts.repoMap = loadMapping(repoMap)
+			}
+			migrateProject(loadProject(args[0], useDisk), opts)
 		},
 		Args: cobra.ExactArgs(1),
 	}
-	cmd.Flags().StringVar(&destProjectID, "dest", "", "a specific projectID to push results to")
-	cmd.Flags().StringVar(&org, "org", "netlify", "the organization to create the new project in")
+	cmd.Flags().StringVar(&opts.destRef, "dest", "", "a specific projectID to push results to")
+	addProjectOwnerFlags(&cmd, &opts.owner)
 	cmd.Flags().BoolVar(&useDisk, "disk", false, "if the provided project ID is a path to a json file")
+	cmd.Flags().StringVar(&opts.journal, "journal", "", "where to record the migrated columns and cards, defaults to migration-<id>.json")
+	cmd.Flags().BoolVar(&opts.resume, "resume", false, "continue a migration from the journal, skipping what was already migrated")
+	cmd.Flags().StringVar(&repoMap, "repo-map", "", "a csv or yaml file mapping source repos to destination repos to move the cards' issues into")
+	cmd.Flags().StringVar(&opts.issueMode, "issue-mode", issueModeRecreate, "how to move issues into the mapped repos: transfer or recreate")
 
 	return &cmd
 }
 
-func migrateProject(inRef, destRef, org string, useDisk bool) {
-	var proj project
-	if useDisk {
-		bs, err := ioutil.ReadFile(inRef)
-		panicOnErr(err)
-		panicOnErr(json.Unmarshal(bs, &proj))
-		log.Debug("loaded project definition from disk")
-	} else {
-		proj = *queryProject(inRef, false)
+type migrateOpts struct {
+	destRef string
+	owner   projectOwner
+	journal string
+	resume  bool
+
+	repoMap   map[string]string
+	issueMode string
+
+	// a hash of the file being imported, the IDs in the journal are only good for the same file
+	sourceHash string
+}
+
+func migrateProject(proj project, opts migrateOpts) {
+	journal := openJournal(opts.journal, opts.resume)
+	if journal.SourceHash != opts.sourceHash && journal.DestProject != "" {
+		panic(fmt.Sprintf("the journal %s is for a different version of the file, it can't be resumed", opts.journal))
+	}
+	journal.SourceHash = opts.sourceHash
+	if journal.DestProject != "" {
+		if opts.destRef != "" && opts.destRef != journal.DestProject {
+			panic(fmt.Sprintf("the journal is for project %s, not %s", journal.DestProject, opts.destRef))
+		}
+		opts.destRef = journal.DestProject
+		log.Info("resuming the migration", zap.String("dest", opts.destRef), zap.Int("cards_migrated", len(journal.Cards)))
 	}
 
+	destRef := opts.destRef
 	if destRef == "" {
-		destRef = createNewProject(proj, org)
+		destRef = createNewProject(proj, opts.owner)
 		log.Debug("created new project board", zap.String("id", destRef))
 	}
+	journal.DestProject = destRef
+	journal.save()
 
-	var cardsCreated int
+	var cardsCreated, cardsSkipped int
 	for _, col := range proj.Cols {
-		newColID := createColumn(destRef, col)
-		log.Debug("created new column", zap.String("id", newColID))
+		newColID, ok := journal.Columns[col.ID]
+		if !ok {
+			newColID = createColumn(destRef, col)
+			moveColumn(newColID, "last")
+			log.Debug("created new column", zap.String("id", newColID))
+			journal.Columns[col.ID] = newColID
+			journal.save()
+		}
 		for _, card := range col.Cards {
-			newCardID := createCard(newColID, card)
-			log.Debug("created new card", zap.String("id", newCardID))
+			if _, ok := journal.Cards[card.ID]; ok {
+				cardsSkipped++
+				continue
+			}
+			// new cards go to the top, so move each one to the bottom to keep the order
+			newCardID := createCard(newColID, mapCardContent(card, opts.repoMap, opts.issueMode, journal))
+			moveCard(newCardID, newColID, "bottom")
+			if card.Archived {
+				setCardArchived(newCardID, true)
+			}
+			log.Debug("created new card", zap.String("id", newCardID), zap.Bool("archived", card.Archived))
+			journal.Cards[card.ID] = newCardID
+			journal.save()
 			cardsCreated++
 		}
 	}
 
-	log.Info("migrated the board, columns, and cards", zap.Int("cards_created", cardsCreated))
+	log.Info("migrated the board, columns, and cards",
+		zap.Int("cards_created", cardsCreated),
+		zap.Int("cards_skipped", cardsSkipped),
+	)
+}
+
+// loadProject reads the project and its columns either from github or from a json dump on disk
+func loadProject(inRef string, useDisk bool) project {
+	var proj project
+	if useDisk {
+		bs, err := ioutil.ReadFile(inRef)
+		panicOnErr(err)
+		panicOnErr(json.Unmarshal(bs, &proj))
+		log.Debug("loaded project definition from disk")
+	} else {
+		proj = *queryProject(inRef, false)
+	}
+	return proj
 }
 
 func queryProject(id string, shallow bool) *project {
 	code, raw := queryGitHub(fmt.Sprintf("/projects/%s", id))
 	if code != http.StatusOK {
-		log.Info("Got a !200 response, assuming we got all the pages")
-		return nil
+		// every caller needs the project, so fail here with the id instead of on a nil
+		panic(fmt.Sprintf("failed to get project %s: unexpected response code: %d: %s", id, code, string(raw)))
 	}
 	var proj project
 	panicOnErr(json.Unmarshal(raw, &proj))
@@ -163,10 +314,9 @@ func queryProject(id string, shallow bool) *project {
 	return &proj
 }
 
-func readProjectPages(org string, iter func(p project) error) {
-	log.Debug("going to list the project page by page", zap.String("org", org))
+func readProjectPages(path string, iter func(p project) error) {
+	log.Debug("going to list the project page by page", zap.String("path", path))
 	projectsProcessed := 0
-	path := fmt.Sprintf("/orgs/%s/projects", org)
 	queryByPage(path, func(raw []byte) bool {
 		objs := []project{}
 		panicOnErr(json.Unmarshal(raw, &objs))
@@ -203,7 +353,8 @@ func fetchCols(projID int) []*projectColumn {
 	cols := []*projectColumn{}
 	panicOnErr(json.Unmarshal(raw, &cols))
 	for _, col := range cols {
-		path = fmt.Sprintf("/projects/columns/%d/cards", col.ID)
+		// archived cards are hidden unless we ask for them
+		path = fmt.Sprintf("/projects/columns/%d/cards?archived_state=all", col.ID)
 		queryByPage(path, func(raw []byte) bool {
 			cards := []projectCard{}
 			panicOnErr(json.Unmarshal(raw, &cards))
@@ -222,7 +373,7 @@ func fetchCols(projID int) []*projectColumn {
 	return cols
 }
 
-func createNewProject(original project, org string) string {
+func createNewProject(original project, owner projectOwner) string {
 	body, err := json.Marshal(&struct {
 		Name string `json:"name"`
 		Body string `json:"body"`
@@ -232,7 +383,7 @@ func createNewProject(original project, org string) string {
 	})
 	panicOnErr(err)
 
-	code, raw := queryGitHub(fmt.Sprintf("/orgs/%s/projects", org),
+	code, raw := queryGitHub(owner.createPath(),
 		withMethod(http.MethodPost),
 		withPayload(body),
 	)
@@ -305,3 +456,52 @@ func createCard(columnID string, card projectCard) string {
 
 	return strconv.Itoa(out.ID)
 }
+
+func moveColumn(columnID, position string) {
+	body, err := json.Marshal(&struct {
+		Position string `json:"position"`
+	}{
+		Position: position,
+	})
+	panicOnErr(err)
+
+	code, raw := queryGitHub(fmt.Sprintf("/projects/columns/%s/moves", columnID),
+		withMethod(http.MethodPost),
+		withPayload(body),
+	)
+	requireCode(201, code, raw)
+}
+
+func moveCard(cardID, columnID, position string) {
+	colID, err := strconv.Atoi(columnID)
+	panicOnErr(err)
+	body, err := json.Marshal(&struct {
+		Position string `json:"position"`
+		ColumnID int    `json:"column_id"`
+	}{
+		Position: position,
+		ColumnID: colID,
+	})
+	panicOnErr(err)
+
+	code, raw := queryGitHub(fmt.Sprintf("/projects/columns/cards/%s/moves", cardID),
+		withMethod(http.MethodPost),
+		withPayload(body),
+	)
+	requireCode(201, code, raw)
+}
+
+func setCardArchived(cardID string, archived bool) {
+	body, err := json.Marshal(&struct {
+		Archived bool `json:"archived"`
+	}{
+		Archived: archived,
+	})
+	panicOnErr(err)
+
+	code, raw := queryGitHub(fmt.Sprintf("/projects/columns/cards/%s", cardID),
+		withMethod(http.MethodPatch),
+		withPayload(body),
+	)
+	requireCode(http.StatusOK, code, raw)
+}
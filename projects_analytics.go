@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func analyzeProjectCmd() *cobra.Command {
+	var report, doneColumn string
+	cmd := cobra.Command{
+		Use: "analytics [id]",
+		Run: func(cmd *cobra.Command, args []string) {
+			proj := queryProject(args[0], false)
+			loadCardHistory(proj)
+			switch report {
+			case "dwell":
+				reportDwell(proj)
+			case "throughput":
+				reportThroughput(proj, doneColumn)
+			case "wip":
+				reportWIP(proj)
+			default:
+				panic(fmt.Sprintf("unexpected report: %s", report))
+			}
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&report, "report", "dwell", "the report to produce: dwell, throughput or wip")
+	cmd.Flags().StringVar(&doneColumn, "done", "", "the column that counts as done for throughput, defaults to the last column")
+
+	return &cmd
+}
+
+// cardMove is a card entering a column, an empty column is the card being archived
+type cardMove struct {
+	Column string
+	At     time.Time
+}
+
+// loadCardHistory fills in when each card entered each of its columns. Cards for
+// issues and PRs have events for the moves, notes don't so they are treated as if
+// they've been in their column since they were created. There's no event for
+// archiving a card, so archived cards leave the board when they were last updated.
+func loadCardHistory(proj *project) {
+	for _, col := range proj.Cols {
+		for i := range col.Cards {
+			card := &col.Cards[i]
+			card.History = []cardMove{{Column: col.Name, At: card.CreatedAt}}
+			if card.ContentURL != "" {
+				if moves := fetchCardMoves(proj.ID, card.ContentURL); len(moves) > 0 {
+					card.History = moves
+				}
+			}
+			if card.Archived {
+				last := card.History[len(card.History)-1]
+				at := card.UpdatedAt
+				if at.Before(last.At) {
+					at = last.At
+				}
+				card.History = append(card.History, cardMove{At: at})
+			}
+		}
+		log.Debug("loaded card history", zap.String("column", col.Name), zap.Int("cards", len(col.Cards)))
+	}
+}
+
+func fetchCardMoves(projectID int, contentURL string) []cardMove {
+	match := contentURLPattern.FindStringSubmatch(contentURL)
+	if match == nil {
+		return nil
+	}
+
+	moves := []cardMove{}
+	path := fmt.Sprintf("/repos/%s/issues/%s/events", match[1], match[3])
+	queryByPage(path, func(raw []byte) bool {
+		events := []struct {
+			Event       string
+			CreatedAt   time.Time `json:"created_at"`
+			ProjectCard struct {
+				ProjectID  int    `json:"project_id"`
+				ColumnName string `json:"column_name"`
+			} `json:"project_card"`
+		}{}
+		panicOnErr(json.Unmarshal(raw, &events))
+		for _, e := range events {
+			if e.ProjectCard.ProjectID != projectID {
+				continue
+			}
+			switch e.Event {
+			case "added_to_project", "moved_columns_in_project", "converted_note_to_issue":
+				moves = append(moves, cardMove{Column: e.ProjectCard.ColumnName, At: e.CreatedAt})
+			}
+		}
+		return len(events) != 0
+	}, withAccept("application/vnd.github.starfox-preview+json"))
+
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].At.Before(moves[j].At)
+	})
+	return moves
+}
+
+// columnAt returns the column the card was in at the time, or "" if it wasn't on the board yet
+func columnAt(card projectCard, at time.Time) string {
+	col := ""
+	for _, m := range card.History {
+		if m.At.After(at) {
+			break
+		}
+		col = m.Column
+	}
+	return col
+}
+
+func cardLabel(card projectCard) string {
+	if card.ContentURL != "" {
+		return card.ContentURL
+	}
+	title, _ := splitNote(card.Note)
+	return title
+}
+
+type columnDwell struct {
+	Card    string
+	Column  string
+	Entered time.Time
+	Left    *time.Time `json:",omitempty"`
+	Days    float64
+}
+
+func (d columnDwell) Fields() []csvField {
+	left := ""
+	if d.Left != nil {
+		left = d.Left.Format(time.RFC3339)
+	}
+	return []csvField{
+		{"card", d.Card},
+		{"column", d.Column},
+		{"entered", d.Entered.Format(time.RFC3339)},
+		{"left", left},
+		{"days", fmt.Sprintf("%.2f", d.Days)},
+	}
+}
+
+func reportDwell(proj *project) {
+	now := time.Now()
+	for _, col := range proj.Cols {
+		for _, card := range col.Cards {
+			for i, m := range card.History {
+				if m.Column == "" {
+					continue
+				}
+				d := columnDwell{
+					Card:    cardLabel(card),
+					Column:  m.Column,
+					Entered: m.At,
+				}
+				end := now
+				if i+1 < len(card.History) {
+					end = card.History[i+1].At
+					d.Left = &end
+				}
+				d.Days = end.Sub(m.At).Hours() / 24
+				panicOnErr(enc(d))
+			}
+		}
+	}
+}
+
+type weeklyThroughput struct {
+	Week  string
+	Cards int
+}
+
+func (t weeklyThroughput) Fields() []csvField {
+	return []csvField{
+		{"week", t.Week},
+		{"cards", t.Cards},
+	}
+}
+
+// weekStart is the monday of the week the time is in
+func weekStart(t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func reportThroughput(proj *project, doneColumn string) {
+	if doneColumn == "" && len(proj.Cols) > 0 {
+		doneColumn = proj.Cols[len(proj.Cols)-1].Name
+	}
+
+	weeks := map[string]int{}
+	for _, col := range proj.Cols {
+		for _, card := range col.Cards {
+			for _, m := range card.History {
+				if strings.EqualFold(m.Column, doneColumn) {
+					weeks[weekStart(m.At).Format(filterDateFormat)]++
+					break
+				}
+			}
+		}
+	}
+
+	keys := []string{}
+	for k := range weeks {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		panicOnErr(enc(weeklyThroughput{Week: k, Cards: weeks[k]}))
+	}
+}
+
+type wipCount struct {
+	Week   string
+	Column string
+	Cards  int
+}
+
+func (w wipCount) Fields() []csvField {
+	return []csvField{
+		{"week", w.Week},
+		{"column", w.Column},
+		{"cards", w.Cards},
+	}
+}
+
+// reportWIP counts the cards in each column at the end of every week since the first card was added
+func reportWIP(proj *project) {
+	cards := []projectCard{}
+	var first time.Time
+	for _, col := range proj.Cols {
+		for _, card := range col.Cards {
+			cards = append(cards, card)
+			if len(card.History) > 0 && (first.IsZero() || card.History[0].At.Before(first)) {
+				first = card.History[0].At
+			}
+		}
+	}
+	if first.IsZero() {
+		return
+	}
+
+	now := time.Now()
+	for week := weekStart(first); week.Before(now); week = week.AddDate(0, 0, 7) {
+		end := week.AddDate(0, 0, 7)
+		if end.After(now) {
+			end = now
+		}
+		counts := map[string]int{}
+		for _, card := range cards {
+			if col := columnAt(card, end); col != "" {
+				counts[col]++
+			}
+		}
+		for _, col := range proj.Cols {
+			panicOnErr(enc(wipCount{
+				Week:   week.Format(filterDateFormat),
+				Column: col.Name,
+				Cards:  counts[col.Name],
+			}))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type clearFilter struct {
+	columns        []string
+	closedIssues   bool
+	notesOlderThan int
+}
+
+// cardsOnly is if we should only remove the matching cards and leave the columns
+func (f clearFilter) cardsOnly() bool {
+	return f.closedIssues || f.notesOlderThan > 0
+}
+
+func (f clearFilter) matchesColumn(col *projectColumn) bool {
+	return len(f.columns) == 0 || containsFold(f.columns, col.Name)
+}
+
+func (f clearFilter) matchesCard(card projectCard) bool {
+	if card.Note != "" {
+		return f.notesOlderThan > 0 && time.Since(card.CreatedAt) > time.Duration(f.notesOlderThan)*24*time.Hour
+	}
+	if !f.closedIssues {
+		return false
+	}
+	code, raw := queryGitHub(card.ContentURL)
+	requireCode(http.StatusOK, code, raw)
+	issue := struct {
+		State string
+	}{}
+	panicOnErr(json.Unmarshal(raw, &issue))
+	return issue.State == "closed"
+}
+
+func emptyProjectCmd() *cobra.Command {
+	var filter clearFilter
+	var yes bool
+	var backup string
+	cmd := cobra.Command{
+		Use: "clear [id]",
+		Run: func(cmd *cobra.Command, args []string) {
+			boardID := args[0]
+			proj := queryProject(boardID, false)
+			if backup == "" {
+				backup = fmt.Sprintf("project-%s-%s.json", boardID, time.Now().Format("20060102-150405"))
+			}
+			backupProject(proj, backup)
+			clearProject(proj, filter, yes)
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().BoolVar(&yes, "yes", false, "clear the project without asking")
+	cmd.Flags().StringVar(&backup, "backup", "", "where to write a snapshot of the project, defaults to project-<id>-<timestamp>.json")
+	cmd.Flags().StringSliceVar(&filter.columns, "column", nil, "only clear the columns with these names")
+	cmd.Flags().BoolVar(&filter.closedIssues, "closed-issues", false, "only remove cards for closed issues and PRs")
+	cmd.Flags().IntVar(&filter.notesOlderThan, "notes-older-than", 0, "only remove notes created more than this many days ago")
+	return &cmd
+}
+
+// backupProject writes the project in the format migrate --disk reads
+func backupProject(proj *project, path string) {
+	bs, err := json.MarshalIndent(proj, "", "  ")
+	panicOnErr(err)
+	panicOnErr(ioutil.WriteFile(path, bs, 0644))
+	log.Info("wrote a backup of the project", zap.String("path", path))
+}
+
+func clearProject(proj *project, filter clearFilter, yes bool) {
+	cols := []*projectColumn{}
+	cards := []projectCard{}
+	for _, c := range proj.Cols {
+		if !filter.matchesColumn(c) {
+			continue
+		}
+		cols = append(cols, c)
+		if filter.cardsOnly() {
+			for _, card := range c.Cards {
+				if filter.matchesCard(card) {
+					cards = append(cards, card)
+				}
+			}
+		}
+	}
+
+	prompt := fmt.Sprintf("remove %d columns from %s?", len(cols), proj.Name)
+	if filter.cardsOnly() {
+		prompt = fmt.Sprintf("remove %d cards from %s?", len(cards), proj.Name)
+	}
+	if !yes && !confirm(prompt) {
+		log.Info("not clearing anything")
+		return
+	}
+
+	if filter.cardsOnly() {
+		for _, card := range cards {
+			log.Debug("removing card", zap.Int("id", card.ID))
+			code, raw := queryGitHub(fmt.Sprintf("/projects/columns/cards/%d", card.ID),
+				withMethod(http.MethodDelete),
+			)
+			requireCode(204, code, raw)
+		}
+		return
+	}
+
+	for _, c := range cols {
+		log.Debug("removing column", zap.String("name", c.Name), zap.Int("id", c.ID))
+		code, raw := queryGitHub(fmt.Sprintf("/projects/columns/%d", c.ID),
+			withMethod(http.MethodDelete),
+		)
+		requireCode(204, code, raw)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func exportProjectCmd() *cobra.Command {
+	var includeArchived bool
+	cmd := cobra.Command{
+		Use:   "export [id]",
+		Short: "export a project to the --format: markdown, csv or html, defaults to markdown",
+		// the export writes the whole document itself, the encoder's finisher would add to it
+		PreRun: func(cmd *cobra.Command, args []string) {
+			encFinish = nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			exportFormat := format
+			if exportFormat == "" {
+				exportFormat = formatMarkdown
+			}
+			render, ok := projectRenderers[exportFormat]
+			if !ok {
+				panic(fmt.Sprintf("unexpected export format: %s", exportFormat))
+			}
+			board := buildExportBoard(*queryProject(args[0], false), includeArchived)
+			panicOnErr(render(out, board))
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().BoolVar(&includeArchived, "include-archived", false, "include the archived cards")
+
+	return &cmd
+}
+
+type exportBoard struct {
+	Name    string
+	Body    string
+	URL     string
+	Columns []exportColumn
+}
+
+type exportColumn struct {
+	Name  string
+	Cards []exportCard
+}
+
+type exportCard struct {
+	Title     string
+	URL       string
+	Number    int
+	State     string
+	Assignees []string
+	Labels    []string
+	Note      string
+}
+
+func buildExportBoard(proj project, includeArchived bool) exportBoard {
+	board := exportBoard{
+		Name: proj.Name,
+		Body: proj.Body,
+		URL:  proj.HTMLURL,
+	}
+	for _, col := range proj.Cols {
+		ec := exportColumn{Name: col.Name}
+		for _, card := range col.Cards {
+			if card.Archived && !includeArchived {
+				continue
+			}
+			ec.Cards = append(ec.Cards, resolveExportCard(card))
+		}
+		board.Columns = append(board.Columns, ec)
+	}
+	return board
+}
+
+// resolveExportCard fills in the details of the issue or PR behind the card
+func resolveExportCard(card projectCard) exportCard {
+	if card.Note != "" {
+		title, _ := splitNote(card.Note)
+		return exportCard{Title: title, Note: card.Note}
+	}
+
+	code, raw := queryGitHub(card.ContentURL)
+	if code != http.StatusOK {
+		log.Warn("failed to fetch the card's content", zap.String("url", card.ContentURL), zap.Int("status", code))
+		return exportCard{Title: card.ContentURL}
+	}
+	content := struct {
+		Title     string
+		Number    int
+		State     string
+		HTMLURL   string `json:"html_url"`
+		Assignees []struct {
+			Login string
+		}
+		Labels []struct {
+			Name string
+		}
+	}{}
+	panicOnErr(json.Unmarshal(raw, &content))
+
+	ec := exportCard{
+		Title:  content.Title,
+		URL:    content.HTMLURL,
+		Number: content.Number,
+		State:  content.State,
+	}
+	for _, a := range content.Assignees {
+		ec.Assignees = append(ec.Assignees, a.Login)
+	}
+	for _, l := range content.Labels {
+		ec.Labels = append(ec.Labels, l.Name)
+	}
+	return ec
+}
+
+type projectRenderer func(w io.Writer, board exportBoard) error
+
+var projectRenderers = map[string]projectRenderer{
+	formatMarkdown: renderProjectMarkdown,
+	formatCSV:      renderProjectCSV,
+	formatHTML:     renderProjectHTML,
+}
+
+func renderProjectMarkdown(w io.Writer, board exportBoard) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", board.Name)
+	if board.Body != "" {
+		fmt.Fprintf(&b, "%s\n\n", board.Body)
+	}
+	for _, col := range board.Columns {
+		fmt.Fprintf(&b, "## %s (%d)\n\n", col.Name, len(col.Cards))
+		for _, c := range col.Cards {
+			if c.Note != "" {
+				// indent the rest of the note so it stays in the list item
+				fmt.Fprintf(&b, "- %s\n", strings.ReplaceAll(strings.TrimSpace(c.Note), "\n", "\n  "))
+				continue
+			}
+			line := fmt.Sprintf("- [%s](%s)", c.Title, c.URL)
+			if c.Number != 0 {
+				line += fmt.Sprintf(" #%d", c.Number)
+			}
+			if c.State != "" {
+				line += " · " + c.State
+			}
+			if len(c.Assignees) > 0 {
+				line += " · @" + strings.Join(c.Assignees, ", @")
+			}
+			if len(c.Labels) > 0 {
+				line += " · `" + strings.Join(c.Labels, "` `") + "`"
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func renderProjectCSV(w io.Writer, board exportBoard) error {
+	writer := csv.NewWriter(w)
+	panicOnErr(writer.Write([]string{"column", "title", "number", "state", "assignees", "labels", "url", "note"}))
+	for _, col := range board.Columns {
+		for _, c := range col.Cards {
+			number := ""
+			if c.Number != 0 {
+				number = fmt.Sprintf("%d", c.Number)
+			}
+			panicOnErr(writer.Write([]string{
+				col.Name,
+				c.Title,
+				number,
+				c.State,
+				strings.Join(c.Assignees, ","),
+				strings.Join(c.Labels, ","),
+				c.URL,
+				c.Note,
+			}))
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+var projectHTMLTemplate = template.Must(template.New("project").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; }
+.board { display: flex; gap: 1em; align-items: flex-start; }
+.column { background: #f6f8fa; border-radius: 6px; padding: 0.5em; min-width: 250px; }
+.card { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; padding: 0.5em; margin: 0.5em 0; }
+.meta { color: #57606a; font-size: 0.85em; }
+.label { background: #ddf4ff; border-radius: 1em; padding: 0 0.5em; margin-right: 0.25em; }
+.note { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{if .URL}}<a href="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}</h1>
+{{if .Body}}<p>{{.Body}}</p>{{end}}
+<div class="board">
+{{range .Columns}}<div class="column">
+<h2>{{.Name}} ({{len .Cards}})</h2>
+{{range .Cards}}<div class="card">
+{{if .Note}}<div class="note">{{.Note}}</div>{{else}}<a href="{{.URL}}">{{.Title}}</a>
+<div class="meta">{{if .Number}}#{{.Number}} · {{end}}{{.State}}{{if .Assignees}} · @{{join .Assignees ", @"}}{{end}}</div>
+{{if .Labels}}<div>{{range .Labels}}<span class="label">{{.}}</span>{{end}}</div>{{end}}{{end}}
+</div>
+{{end}}</div>
+{{end}}</div>
+</body>
+</html>
+`))
+
+func renderProjectHTML(w io.Writer, board exportBoard) error {
+	return projectHTMLTemplate.Execute(w, board)
+}
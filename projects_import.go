@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func importProjectCmd() *cobra.Command {
+	var name string
+	var opts migrateOpts
+	cmd := cobra.Command{
+		Use: "import [file]",
+		Run: func(cmd *cobra.Command, args []string) {
+			proj := loadProjectImport(args[0])
+			if name != "" {
+				proj.Name = name
+			}
+			if opts.journal == "" {
+				opts.journal = defaultJournalPath(args[0])
+			}
+			opts.sourceHash = hashFile(args[0])
+			migrateProject(proj, opts)
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&name, "name", "", "the name of the new project, defaults to the name in the file or the file name")
+	cmd.Flags().StringVar(&opts.destRef, "dest", "", "a specific projectID to push results to")
+	addProjectOwnerFlags(&cmd, &opts.owner)
+	cmd.Flags().StringVar(&opts.journal, "journal", "", "where to record the imported columns and cards, defaults to migration-<file>.json")
+	cmd.Flags().BoolVar(&opts.resume, "resume", false, "continue an import from the journal, skipping what was already imported")
+
+	return &cmd
+}
+
+// projectImport is the yaml layout of an import, cards are either the text of a
+// note or an issue reference like owner/repo#123
+type projectImport struct {
+	Name    string                `yaml:"name"`
+	Body    string                `yaml:"body"`
+	Columns []projectImportColumn `yaml:"columns"`
+}
+
+type projectImportColumn struct {
+	Name  string   `yaml:"name"`
+	Cards []string `yaml:"cards"`
+}
+
+// loadProjectImport reads a yaml import or a csv with the headers: column, card.
+// The columns and cards are given IDs in the order of the file so the journal
+// can track them, which is why resuming needs the file to be unchanged.
+func loadProjectImport(file string) project {
+	var in projectImport
+	if isYAMLFile(file) {
+		loadYAML(file, &in)
+	} else {
+		colIndex := map[string]int{}
+		for _, row := range loadCSVRecords(file) {
+			idx, ok := colIndex[row["column"]]
+			if !ok {
+				idx = len(in.Columns)
+				colIndex[row["column"]] = idx
+				in.Columns = append(in.Columns, projectImportColumn{Name: row["column"]})
+			}
+			if row["card"] != "" {
+				in.Columns[idx].Cards = append(in.Columns[idx].Cards, row["card"])
+			}
+		}
+	}
+	if in.Name == "" {
+		in.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+
+	proj := project{
+		Name: in.Name,
+		Body: in.Body,
+	}
+	nextID := 1
+	for _, c := range in.Columns {
+		col := &projectColumn{ID: nextID, Name: c.Name}
+		nextID++
+		for _, text := range c.Cards {
+			card := resolveCardRef(text)
+			card.ID = nextID
+			nextID++
+			col.Cards = append(col.Cards, card)
+		}
+		proj.Cols = append(proj.Cols, col)
+	}
+	log.Debug("loaded project import", zap.String("file", file), zap.Int("columns", len(proj.Cols)))
+	return proj
+}
+
+func hashFile(file string) string {
+	bs, err := ioutil.ReadFile(file)
+	panicOnErr(err)
+	return fmt.Sprintf("%x", sha256.Sum256(bs))
+}
+
+var issueRefPattern = regexp.MustCompile(`^([\w.-]+/[\w.-]+)#(\d+)$`)
+
+// resolveCardRef turns an owner/repo#123 reference into a card for that issue or
+// PR, anything else is a note
+func resolveCardRef(text string) projectCard {
+	match := issueRefPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return projectCard{Note: text}
+	}
+
+	issueURL := fmt.Sprintf("https://api.github.com/repos/%s/issues/%s", match[1], match[2])
+	code, raw := queryGitHub(issueURL)
+	if code == http.StatusNotFound {
+		panic(fmt.Sprintf("can't find the issue or PR for %s", text))
+	}
+	requireCode(http.StatusOK, code, raw)
+	issue := struct {
+		PullRequest *struct {
+			URL string
+		} `json:"pull_request"`
+	}{}
+	panicOnErr(json.Unmarshal(raw, &issue))
+
+	// PR cards have to point at the pulls endpoint so they get the right content type
+	if issue.PullRequest != nil {
+		return projectCard{ContentURL: issue.PullRequest.URL}
+	}
+	return projectCard{ContentURL: issueURL}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	issueModeTransfer = "transfer"
+	issueModeRecreate = "recreate"
+)
+
+type issueMapping struct {
+	OldURL string `json:"old_url"`
+	NewURL string `json:"new_url"`
+	Mode   string
+}
+
+func (m issueMapping) Fields() []csvField {
+	return []csvField{
+		{"old url", m.OldURL},
+		{"new url", m.NewURL},
+		{"mode", m.Mode},
+	}
+}
+
+var contentURLPattern = regexp.MustCompile(`/repos/([^/]+/[^/]+)/(issues|pulls)/(\d+)$`)
+
+// contentRepo pulls the owner/repo out of a card's content url
+func contentRepo(contentURL string) string {
+	match := contentURLPattern.FindStringSubmatch(contentURL)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+type issueDetails struct {
+	NodeID      string `json:"node_id"`
+	Number      int
+	Title       string
+	Body        string
+	State       string
+	HTMLURL     string `json:"html_url"`
+	URL         string
+	PullRequest *struct {
+		URL string
+	} `json:"pull_request"`
+	Labels []struct {
+		Name string
+	}
+}
+
+// mapCardContent moves the issue behind the card into the mapped destination repo,
+// either with a real transfer or by recreating it, and returns the new content url.
+// Cards for repos that aren't mapped are returned as is.
+func mapCardContent(card projectCard, repoMap map[string]string, mode string, journal *migrationJournal) projectCard {
+	if card.Note != "" || len(repoMap) == 0 {
+		return card
+	}
+	destRepo, ok := repoMap[contentRepo(card.ContentURL)]
+	if !ok {
+		return card
+	}
+	if mapped, ok := journal.Issues[card.ContentURL]; ok {
+		card.ContentURL = mapped
+		return card
+	}
+
+	// cards for PRs point at the pulls endpoint, but the issue endpoint has everything we need
+	code, raw := queryGitHub(strings.Replace(card.ContentURL, "/pulls/", "/issues/", 1))
+	requireCode(http.StatusOK, code, raw)
+	var issue issueDetails
+	panicOnErr(json.Unmarshal(raw, &issue))
+
+	// PRs can't be transferred, the best we can do is an issue pointing at them
+	issueMode := mode
+	if issue.PullRequest != nil {
+		issueMode = issueModeRecreate
+	}
+
+	var moved issueDetails
+	switch issueMode {
+	case issueModeTransfer:
+		moved = transferIssue(issue, destRepo)
+	case issueModeRecreate:
+		moved = recreateIssue(issue, destRepo)
+	default:
+		panic(fmt.Sprintf("unexpected issue mode: %s", mode))
+	}
+
+	log.Debug("mapped the card's issue",
+		zap.String("old", issue.HTMLURL),
+		zap.String("new", moved.HTMLURL),
+		zap.String("mode", issueMode),
+	)
+	panicOnErr(enc(issueMapping{
+		OldURL: issue.HTMLURL,
+		NewURL: moved.HTMLURL,
+		Mode:   issueMode,
+	}))
+
+	journal.Issues[card.ContentURL] = moved.URL
+	journal.save()
+	card.ContentURL = moved.URL
+	return card
+}
+
+func transferIssue(issue issueDetails, destRepo string) issueDetails {
+	parts := strings.SplitN(destRepo, "/", 2)
+	if len(parts) != 2 {
+		panic(fmt.Sprintf("expected the destination repo to be owner/name: %s", destRepo))
+	}
+	dest := struct {
+		Repository struct {
+			ID string
+		}
+	}{}
+	queryGraphQL(`query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) { id } }`,
+		map[string]interface{}{"owner": parts[0], "name": parts[1]},
+		&dest,
+	)
+
+	transferred := struct {
+		TransferIssue struct {
+			Issue struct {
+				Number int
+			}
+		}
+	}{}
+	queryGraphQL(`mutation($issue: ID!, $repo: ID!) {
+		transferIssue(input: {issueId: $issue, repositoryId: $repo}) {
+			issue { number }
+		}
+	}`,
+		map[string]interface{}{"issue": issue.NodeID, "repo": dest.Repository.ID},
+		&transferred,
+	)
+
+	code, raw := queryGitHub(fmt.Sprintf("/repos/%s/issues/%d", destRepo, transferred.TransferIssue.Issue.Number))
+	requireCode(http.StatusOK, code, raw)
+	var out issueDetails
+	panicOnErr(json.Unmarshal(raw, &out))
+	return out
+}
+
+func recreateIssue(issue issueDetails, destRepo string) issueDetails {
+	labels := []string{}
+	for _, l := range issue.Labels {
+		labels = append(labels, l.Name)
+	}
+	body, err := json.Marshal(&struct {
+		Title  string   `json:"title"`
+		Body   string   `json:"body"`
+		Labels []string `json:"labels,omitempty"`
+	}{
+		Title:  issue.Title,
+		Body:   fmt.Sprintf("%s\n\n_Migrated from %s_", issue.Body, issue.HTMLURL),
+		Labels: labels,
+	})
+	panicOnErr(err)
+
+	code, raw := queryGitHub(fmt.Sprintf("/repos/%s/issues", destRepo),
+		withMethod(http.MethodPost),
+		withPayload(body),
+	)
+	requireCode(http.StatusCreated, code, raw)
+	var out issueDetails
+	panicOnErr(json.Unmarshal(raw, &out))
+
+	if issue.State == "closed" {
+		body, err := json.Marshal(&struct {
+			State string `json:"state"`
+		}{
+			State: "closed",
+		})
+		panicOnErr(err)
+		code, raw := queryGitHub(out.URL,
+			withMethod(http.MethodPatch),
+			withPayload(body),
+		)
+		requireCode(http.StatusOK, code, raw)
+	}
+	return out
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// migrationJournal records what a project migration has already created, so a
+// migration that failed part way through can be resumed without duplicating anything.
+type migrationJournal struct {
+	path string
+
+	DestProject string         `json:"dest_project"`
+	Columns     map[int]string `json:"columns"`
+	Cards       map[int]string `json:"cards"`
+
+	// the content url of the original issue to the one it was moved to
+	Issues map[string]string `json:"issues,omitempty"`
+
+	SourceHash string `json:"source_hash,omitempty"`
+}
+
+func defaultJournalPath(inRef string) string {
+	name := strings.TrimSuffix(filepath.Base(inRef), filepath.Ext(inRef))
+	return fmt.Sprintf("migration-%s.json", name)
+}
+
+// openJournal loads the journal when resuming, otherwise it starts a new one and
+// refuses to clobber an existing journal
+func openJournal(path string, resume bool) *migrationJournal {
+	journal := &migrationJournal{
+		path:    path,
+		Columns: map[int]string{},
+		Cards:   map[int]string{},
+		Issues:  map[string]string{},
+	}
+
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if resume {
+			panic(fmt.Sprintf("can't resume, the journal %s doesn't exist", path))
+		}
+		return journal
+	}
+	panicOnErr(err)
+	if !resume {
+		panic(fmt.Sprintf("the journal %s already exists, use --resume to continue that migration or remove it", path))
+	}
+
+	panicOnErr(json.Unmarshal(bs, journal))
+	return journal
+}
+
+// save writes the journal to a temp file first so a crash can't leave it half written
+func (j *migrationJournal) save() {
+	bs, err := json.MarshalIndent(j, "", "  ")
+	panicOnErr(err)
+	tmp := j.path + ".tmp"
+	panicOnErr(ioutil.WriteFile(tmp, bs, 0644))
+	panicOnErr(os.Rename(tmp, j.path))
+}
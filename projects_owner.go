@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// projectOwner is where classic projects live: an org, a repo, or a user
+type projectOwner struct {
+	org  string
+	repo string
+	user string
+}
+
+func addProjectOwnerFlags(cmd *cobra.Command, owner *projectOwner) {
+	cmd.Flags().StringVar(&owner.org, "org", "netlify", "the organization that owns the projects")
+	cmd.Flags().StringVar(&owner.repo, "repo", "", "use the projects of this owner/repo instead of the org")
+	cmd.Flags().StringVar(&owner.user, "user", "", "use the projects of this user instead of the org")
+}
+
+func (o projectOwner) listPath() string {
+	switch {
+	case o.repo != "":
+		return fmt.Sprintf("/repos/%s/projects", qualifyRepo(o.repo))
+	case o.user != "":
+		return fmt.Sprintf("/users/%s/projects", o.user)
+	}
+	return fmt.Sprintf("/orgs/%s/projects", o.org)
+}
+
+// createPath is where new projects are created, github only allows creating user
+// projects for the user of the token
+func (o projectOwner) createPath() string {
+	switch {
+	case o.repo != "":
+		return fmt.Sprintf("/repos/%s/projects", qualifyRepo(o.repo))
+	case o.user != "":
+		if login := tokenLogin(); !strings.EqualFold(login, o.user) {
+			panic(fmt.Sprintf("can only create projects for the user of the token, %s, not %s", login, o.user))
+		}
+		return "/user/projects"
+	}
+	return fmt.Sprintf("/orgs/%s/projects", o.org)
+}
+
+// tokenLogin is the login of the user the token belongs to
+func tokenLogin() string {
+	code, raw := queryGitHub("/user")
+	requireCode(http.StatusOK, code, raw)
+	user := struct {
+		Login string
+	}{}
+	panicOnErr(json.Unmarshal(raw, &user))
+	return user.Login
+}
+
+// listAllRepoProjects walks every repo in the org and lists its projects. Repos
+// with projects disabled respond with a 410, which ends the paging for that repo.
+func listAllRepoProjects() {
+	var total int
+	readRepoPages(func(r repo) error {
+		readProjectPages(fmt.Sprintf("/repos/%s/projects", r.Name), func(p project) error {
+			p.Repo = r.Name
+			total++
+			return enc(&p)
+		})
+		return nil
+	})
+	log.Info("finished listing the repo projects", zap.Int("projects", total))
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func syncProjectCmd() *cobra.Command {
+	var dryRun bool
+	var interval time.Duration
+	cmd := cobra.Command{
+		Use: "sync [src] [dest]",
+		Run: func(cmd *cobra.Command, args []string) {
+			for {
+				syncProject(args[0], args[1], dryRun)
+				if interval == 0 {
+					return
+				}
+				flushReport()
+				log.Info("waiting for the next sync", zap.Duration("interval", interval))
+				time.Sleep(interval)
+			}
+		},
+		Args: cobra.ExactArgs(2),
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the changes that would be made")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "keep syncing on this interval instead of only once")
+
+	return &cmd
+}
+
+const (
+	syncAddColumn    = "add_column"
+	syncMoveColumn   = "move_column"
+	syncRemoveColumn = "remove_column"
+	syncAddCard      = "add_card"
+	syncMoveCard     = "move_card"
+	syncRemoveCard   = "remove_card"
+	syncRestoreCard  = "restore_card"
+)
+
+type syncChange struct {
+	Action   string
+	Column   string
+	Card     string `json:",omitempty"`
+	Position string `json:",omitempty"`
+}
+
+func (c syncChange) Fields() []csvField {
+	return []csvField{
+		{"action", c.Action},
+		{"column", c.Column},
+		{"card", c.Card},
+		{"position", c.Position},
+	}
+}
+
+// syncCardKeys identifies the same card across boards, the issue it points to or
+// its note. Notes can have the same text, so they're also keyed by how many
+// notes with that text came before them on the board.
+type syncCardKeys map[string]int
+
+func (k syncCardKeys) key(card projectCard) string {
+	if card.ContentURL != "" {
+		return card.ContentURL
+	}
+	n := k[card.Note]
+	k[card.Note]++
+	if n == 0 {
+		return "note:" + card.Note
+	}
+	return fmt.Sprintf("note:%s#%d", card.Note, n+1)
+}
+
+// destCard is where a card currently sits on the destination board
+type destCard struct {
+	id       string
+	column   string
+	archived bool
+}
+
+type projectSyncer struct {
+	dryRun  bool
+	destRef string
+	changes int
+
+	// column name to ID and the card keys in each column on the destination board
+	colIDs   map[string]string
+	colOrder []string
+	cards    map[string]destCard
+	colCards map[string][]string
+}
+
+func syncProject(srcRef, destRef string, dryRun bool) {
+	src := queryProject(srcRef, false)
+	dest := queryProject(destRef, false)
+	s := newProjectSyncer(dest, destRef, dryRun)
+	s.sync(src)
+
+	log.Info("synced the project", zap.String("src", srcRef), zap.String("dest", destRef), zap.Int("changes", s.changes), zap.Bool("dry_run", dryRun))
+}
+
+// newProjectSyncer indexes the columns and cards already on the destination board
+func newProjectSyncer(dest *project, destRef string, dryRun bool) *projectSyncer {
+	s := &projectSyncer{
+		dryRun:   dryRun,
+		destRef:  destRef,
+		colIDs:   map[string]string{},
+		cards:    map[string]destCard{},
+		colCards: map[string][]string{},
+	}
+	destKeys := syncCardKeys{}
+	for _, col := range dest.Cols {
+		s.colIDs[col.Name] = strconv.Itoa(col.ID)
+		s.colOrder = append(s.colOrder, col.Name)
+		for _, card := range col.Cards {
+			if card.Archived {
+				// an issue can only be on a board once, so an archived card for it is
+				// restored rather than added again. Archived notes are left alone.
+				if card.ContentURL != "" {
+					s.cards[card.ContentURL] = destCard{id: strconv.Itoa(card.ID), column: col.Name, archived: true}
+				}
+				continue
+			}
+			key := destKeys.key(card)
+			s.cards[key] = destCard{id: strconv.Itoa(card.ID), column: col.Name}
+			s.colCards[col.Name] = append(s.colCards[col.Name], key)
+		}
+	}
+	return s
+}
+
+// sync makes the destination board match the source board
+func (s *projectSyncer) sync(src *project) {
+	srcCols := map[string]bool{}
+	srcKeys := map[string]bool{}
+	prevCol := ""
+	for _, col := range src.Cols {
+		srcCols[col.Name] = true
+		s.syncColumn(col, prevCol)
+		prevCol = col.Name
+	}
+
+	// cards go first, so anything that moved out of a removed column survives
+	keys := syncCardKeys{}
+	for _, col := range src.Cols {
+		prevCard := ""
+		for _, card := range col.Cards {
+			if card.Archived {
+				continue
+			}
+			key := keys.key(card)
+			srcKeys[key] = true
+			s.syncCard(col.Name, key, card, prevCard)
+			prevCard = key
+		}
+	}
+	destKeys := []string{}
+	for key := range s.cards {
+		destKeys = append(destKeys, key)
+	}
+	sort.Strings(destKeys)
+	for _, key := range destKeys {
+		if card := s.cards[key]; !srcKeys[key] && !card.archived {
+			s.apply(syncChange{Action: syncRemoveCard, Column: card.column, Card: key}, func() {
+				deleteProjectItem(fmt.Sprintf("/projects/columns/cards/%s", card.id))
+			})
+		}
+	}
+	for _, name := range s.colOrder {
+		if !srcCols[name] {
+			id := s.colIDs[name]
+			s.apply(syncChange{Action: syncRemoveColumn, Column: name}, func() {
+				deleteProjectItem(fmt.Sprintf("/projects/columns/%s", id))
+			})
+		}
+	}
+}
+
+func (s *projectSyncer) apply(change syncChange, do func()) {
+	s.changes++
+	panicOnErr(enc(change))
+	if !s.dryRun {
+		do()
+	}
+}
+
+// syncColumn makes sure the column exists and comes right after the previous one
+func (s *projectSyncer) syncColumn(col *projectColumn, prevCol string) {
+	position := "first"
+	if prevCol != "" {
+		position = "after:" + s.colIDs[prevCol]
+	}
+
+	if _, ok := s.colIDs[col.Name]; !ok {
+		s.colIDs[col.Name] = ""
+		s.apply(syncChange{Action: syncAddColumn, Column: col.Name, Position: position}, func() {
+			s.colIDs[col.Name] = createColumn(s.destRef, col)
+			moveColumn(s.colIDs[col.Name], position)
+		})
+		s.colOrder = insertAfter(removeValue(s.colOrder, col.Name), col.Name, prevCol)
+		return
+	}
+
+	if indexOf(s.colOrder, col.Name) != indexOf(s.colOrder, prevCol)+1 {
+		s.apply(syncChange{Action: syncMoveColumn, Column: col.Name, Position: position}, func() {
+			moveColumn(s.colIDs[col.Name], position)
+		})
+		s.colOrder = insertAfter(removeValue(s.colOrder, col.Name), col.Name, prevCol)
+	}
+}
+
+// syncCard makes sure the card is in the column right after the previous card
+func (s *projectSyncer) syncCard(column, key string, card projectCard, prevCard string) {
+	position := "top"
+	if prevCard != "" {
+		position = "after:" + s.cards[prevCard].id
+	}
+
+	current, ok := s.cards[key]
+	if !ok {
+		s.apply(syncChange{Action: syncAddCard, Column: column, Card: key, Position: position}, func() {
+			id := createCard(s.colIDs[column], card)
+			moveCard(id, s.colIDs[column], position)
+			s.cards[key] = destCard{id: id, column: column}
+		})
+		if s.dryRun {
+			s.cards[key] = destCard{column: column}
+		}
+		s.colCards[column] = insertAfter(s.colCards[column], key, prevCard)
+		return
+	}
+
+	if current.archived {
+		s.apply(syncChange{Action: syncRestoreCard, Column: current.column, Card: key}, func() {
+			setCardArchived(current.id, false)
+		})
+		current.archived = false
+		s.cards[key] = current
+	}
+
+	cards := s.colCards[column]
+	if current.column == column && indexOf(cards, key) == indexOf(cards, prevCard)+1 {
+		return
+	}
+	s.apply(syncChange{Action: syncMoveCard, Column: column, Card: key, Position: position}, func() {
+		moveCard(current.id, s.colIDs[column], position)
+	})
+	s.colCards[current.column] = removeValue(s.colCards[current.column], key)
+	s.colCards[column] = insertAfter(removeValue(s.colCards[column], key), key, prevCard)
+	s.cards[key] = destCard{id: current.id, column: column}
+}
+
+func deleteProjectItem(path string) {
+	code, raw := queryGitHub(path, withMethod(http.MethodDelete))
+	requireCode(http.StatusNoContent, code, raw)
+}
+
+func indexOf(vals []string, val string) int {
+	for i, v := range vals {
+		if v == val {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeValue(vals []string, val string) []string {
+	out := []string{}
+	for _, v := range vals {
+		if v != val {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// insertAfter puts the value after the other one, or at the start if after is ""
+func insertAfter(vals []string, val, after string) []string {
+	idx := indexOf(vals, after) + 1
+	out := append([]string{}, vals[:idx]...)
+	out = append(out, val)
+	return append(out, vals[idx:]...)
+}
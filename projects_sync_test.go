@@ -0,0 +1,187 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestInsertAfter(t *testing.T) {
+	tests := []struct {
+		vals     []string
+		val      string
+		after    string
+		expected []string
+	}{
+		{nil, "x", "", []string{"x"}},
+		{[]string{"a", "b"}, "x", "", []string{"x", "a", "b"}},
+		{[]string{"a", "b"}, "x", "a", []string{"a", "x", "b"}},
+		{[]string{"a", "b"}, "x", "b", []string{"a", "b", "x"}},
+		// something that isn't there puts the value first
+		{[]string{"a", "b"}, "x", "c", []string{"x", "a", "b"}},
+	}
+	for _, tc := range tests {
+		if actual := insertAfter(tc.vals, tc.val, tc.after); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("insertAfter(%v, %q, %q) = %v, expected %v", tc.vals, tc.val, tc.after, actual, tc.expected)
+		}
+	}
+
+	if actual := removeValue([]string{"a", "b", "a"}, "a"); !reflect.DeepEqual(actual, []string{"b"}) {
+		t.Errorf("removeValue didn't remove every a: %v", actual)
+	}
+}
+
+func TestSyncCardKeys(t *testing.T) {
+	keys := syncCardKeys{}
+	cards := []projectCard{
+		{Note: "todo"},
+		{ContentURL: "https://api.github.com/repos/o/r/issues/1"},
+		{Note: "todo"},
+		{Note: "other"},
+		{Note: "todo"},
+	}
+	expected := []string{
+		"note:todo",
+		"https://api.github.com/repos/o/r/issues/1",
+		"note:todo#2",
+		"note:other",
+		"note:todo#3",
+	}
+	for i, card := range cards {
+		if actual := keys.key(card); actual != expected[i] {
+			t.Errorf("card %d has the key %q, expected %q", i, actual, expected[i])
+		}
+	}
+}
+
+func issueCard(id, number int) projectCard {
+	return projectCard{ID: id, ContentURL: issueURL(number)}
+}
+
+func issueURL(number int) string {
+	return "https://api.github.com/repos/o/r/issues/" + strconv.Itoa(number)
+}
+
+func testBoard(cols ...*projectColumn) *project {
+	return &project{Cols: cols}
+}
+
+func TestProjectSync(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      *project
+		dest     *project
+		expected []syncChange
+	}{
+		{
+			name:     "the same boards",
+			src:      testBoard(&projectColumn{Name: "todo", Cards: []projectCard{issueCard(1, 1), issueCard(2, 2)}}),
+			dest:     testBoard(&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{issueCard(11, 1), issueCard(12, 2)}}),
+			expected: []syncChange{},
+		},
+		{
+			name: "cards reordered in a column",
+			src:  testBoard(&projectColumn{Name: "todo", Cards: []projectCard{issueCard(3, 3), issueCard(1, 1), issueCard(2, 2)}}),
+			dest: testBoard(&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{issueCard(11, 1), issueCard(12, 2), issueCard(13, 3)}}),
+			expected: []syncChange{
+				{Action: syncMoveCard, Column: "todo", Card: issueURL(3), Position: "top"},
+			},
+		},
+		{
+			name: "a missing card",
+			src:  testBoard(&projectColumn{Name: "todo", Cards: []projectCard{issueCard(1, 1), issueCard(2, 2)}}),
+			dest: testBoard(&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{issueCard(11, 1)}}),
+			expected: []syncChange{
+				{Action: syncAddCard, Column: "todo", Card: issueURL(2), Position: "after:11"},
+			},
+		},
+		{
+			name: "a card in another column",
+			src: testBoard(
+				&projectColumn{Name: "todo", Cards: []projectCard{issueCard(1, 1), issueCard(2, 2)}},
+				&projectColumn{Name: "done"},
+			),
+			dest: testBoard(
+				&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{issueCard(11, 1)}},
+				&projectColumn{ID: 20, Name: "done", Cards: []projectCard{issueCard(12, 2)}},
+			),
+			expected: []syncChange{
+				{Action: syncMoveCard, Column: "todo", Card: issueURL(2), Position: "after:11"},
+			},
+		},
+		{
+			name: "an extra card and column",
+			src:  testBoard(&projectColumn{Name: "todo", Cards: []projectCard{issueCard(1, 1)}}),
+			dest: testBoard(
+				&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{issueCard(11, 1), issueCard(12, 2)}},
+				&projectColumn{ID: 20, Name: "old"},
+			),
+			expected: []syncChange{
+				{Action: syncRemoveCard, Column: "todo", Card: issueURL(2)},
+				{Action: syncRemoveColumn, Column: "old"},
+			},
+		},
+		{
+			name: "columns reordered and added",
+			src: testBoard(
+				&projectColumn{Name: "todo"},
+				&projectColumn{Name: "doing"},
+				&projectColumn{Name: "done"},
+			),
+			dest: testBoard(
+				&projectColumn{ID: 20, Name: "done"},
+				&projectColumn{ID: 10, Name: "todo"},
+			),
+			expected: []syncChange{
+				{Action: syncMoveColumn, Column: "todo", Position: "first"},
+				{Action: syncAddColumn, Column: "doing", Position: "after:10"},
+			},
+		},
+		{
+			name: "an archived card on the destination",
+			src:  testBoard(&projectColumn{Name: "todo", Cards: []projectCard{issueCard(1, 1), issueCard(2, 2)}}),
+			dest: testBoard(&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{
+				issueCard(11, 1),
+				{ID: 12, ContentURL: issueURL(2), Archived: true},
+			}}),
+			expected: []syncChange{
+				{Action: syncRestoreCard, Column: "todo", Card: issueURL(2)},
+				{Action: syncMoveCard, Column: "todo", Card: issueURL(2), Position: "after:11"},
+			},
+		},
+		{
+			name:     "archived cards on the source",
+			src:      testBoard(&projectColumn{Name: "todo", Cards: []projectCard{issueCard(1, 1), {ID: 2, Note: "old", Archived: true}}}),
+			dest:     testBoard(&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{issueCard(11, 1), {ID: 12, Note: "gone", Archived: true}}}),
+			expected: []syncChange{},
+		},
+		{
+			name: "notes with the same text",
+			src:  testBoard(&projectColumn{Name: "todo", Cards: []projectCard{{ID: 1, Note: "x"}, {ID: 2, Note: "x"}}}),
+			dest: testBoard(&projectColumn{ID: 10, Name: "todo", Cards: []projectCard{{ID: 11, Note: "x"}}}),
+			expected: []syncChange{
+				{Action: syncAddCard, Column: "todo", Card: "note:x#2", Position: "after:11"},
+			},
+		},
+	}
+
+	defer func(orig encoder) {
+		enc = orig
+	}(enc)
+	for _, tc := range tests {
+		changes := []syncChange{}
+		enc = func(obj interface{}) error {
+			changes = append(changes, obj.(syncChange))
+			return nil
+		}
+
+		s := newProjectSyncer(tc.dest, "1", true)
+		s.sync(tc.src)
+		if !reflect.DeepEqual(changes, tc.expected) {
+			t.Errorf("%s: got the changes %+v, expected %+v", tc.name, changes, tc.expected)
+		}
+		if s.changes != len(tc.expected) {
+			t.Errorf("%s: counted %d changes, expected %d", tc.name, s.changes, len(tc.expected))
+		}
+	}
+}
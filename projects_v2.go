@@ -0,0 +1,406 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+type projectV2 struct {
+	ID               string
+	Number           int
+	Title            string
+	URL              string
+	Closed           bool
+	ShortDescription string `json:",omitempty"`
+
+	Repositories  []string         `json:",omitempty"`
+	ProjectFields []projectV2Field `json:"Fields,omitempty"`
+	Items         []projectV2Item  `json:",omitempty"`
+}
+
+type projectV2Field struct {
+	ID         string
+	Name       string
+	DataType   string               `json:"data_type"`
+	Options    []string             `json:",omitempty"`
+	Iterations []projectV2Iteration `json:",omitempty"`
+}
+
+type projectV2Iteration struct {
+	Title     string
+	StartDate string `json:"start_date"`
+	Duration  int
+}
+
+type projectV2Item struct {
+	ID         string
+	Type       string
+	Title      string
+	URL        string `json:",omitempty"`
+	State      string `json:",omitempty"`
+	Repository string `json:",omitempty"`
+
+	Values map[string]interface{} `json:",omitempty"`
+}
+
+func (p projectV2) Fields() []csvField {
+	return []csvField{
+		{"number", p.Number},
+		{"title", p.Title},
+		{"closed", p.Closed},
+		{"description", p.ShortDescription},
+		{"repositories", strings.Join(p.Repositories, ",")},
+		{"url", p.URL},
+	}
+}
+
+// projectV2ValueTypes are the types of field whose values are queried for the items,
+// the built in fields like the title and repository are already columns of their own
+var projectV2ValueTypes = map[string]bool{
+	"TEXT":          true,
+	"NUMBER":        true,
+	"DATE":          true,
+	"SINGLE_SELECT": true,
+	"ITERATION":     true,
+}
+
+// Rows writes a row per item, with a column for each of the project's own fields
+func (p projectV2) Rows() []csvWritable {
+	names := []string{}
+	for _, f := range p.ProjectFields {
+		if !projectV2ValueTypes[f.DataType] {
+			continue
+		}
+		names = append(names, f.Name)
+	}
+
+	rows := []csvWritable{}
+	for _, item := range p.Items {
+		rows = append(rows, projectV2ItemRow{
+			project: p.Number,
+			item:    item,
+			fields:  names,
+		})
+	}
+	return rows
+}
+
+type projectV2ItemRow struct {
+	project int
+	item    projectV2Item
+	fields  []string
+}
+
+func (r projectV2ItemRow) Fields() []csvField {
+	out := []csvField{
+		{"project", r.project},
+		{"type", r.item.Type},
+		{"title", r.item.Title},
+		{"state", r.item.State},
+		{"repository", r.item.Repository},
+		{"url", r.item.URL},
+	}
+	for _, name := range r.fields {
+		val := r.item.Values[name]
+		if val == nil {
+			val = ""
+		}
+		out = append(out, csvField{strings.ToLower(name), val})
+	}
+	return out
+}
+
+func listProjectsV2Cmd() *cobra.Command {
+	var org string
+	cmd := cobra.Command{
+		Use: "list-v2",
+		Run: func(cmd *cobra.Command, args []string) {
+			readProjectV2Pages(org, func(p projectV2) error {
+				return enc(p)
+			})
+		},
+	}
+	cmd.Flags().StringVar(&org, "org", "netlify", "the organization to list the projects of")
+
+	return &cmd
+}
+
+func queryProjectV2Cmd() *cobra.Command {
+	var org string
+	var shallow bool
+	cmd := cobra.Command{
+		Use: "query-v2 [number]",
+		Run: func(cmd *cobra.Command, args []string) {
+			var number int
+			_, err := fmt.Sscanf(args[0], "%d", &number)
+			panicOnErr(err)
+			panicOnErr(enc(queryProjectV2(org, number, shallow)))
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&org, "org", "netlify", "the organization that owns the project")
+	cmd.Flags().BoolVar(&shallow, "shallow", false, "skip fetching the items")
+
+	return &cmd
+}
+
+type pageInfo struct {
+	HasNextPage bool
+	EndCursor   string
+}
+
+func readProjectV2Pages(org string, iter func(p projectV2) error) {
+	log.Debug("going to list the projects v2 page by page", zap.String("org", org))
+	projectsProcessed := 0
+	var cursor *string
+	for {
+		page := struct {
+			Organization struct {
+				ProjectsV2 struct {
+					PageInfo pageInfo
+					Nodes    []projectV2
+				}
+			}
+		}{}
+		queryGraphQL(`query($org: String!, $cursor: String) {
+			organization(login: $org) {
+				projectsV2(first: 100, after: $cursor) {
+					pageInfo { hasNextPage endCursor }
+					nodes { id number title url closed shortDescription }
+				}
+			}
+		}`,
+			map[string]interface{}{"org": org, "cursor": cursor},
+			&page,
+		)
+
+		conn := page.Organization.ProjectsV2
+		for _, p := range conn.Nodes {
+			if skipArchive && p.Closed {
+				log.Debug("skipping closed project", zap.String("project", p.Title))
+				continue
+			}
+			panicOnErr(iter(p))
+			projectsProcessed++
+			if limit != 0 && projectsProcessed >= limit {
+				log.Debug("Reached configured limit")
+				return
+			}
+		}
+		if !conn.PageInfo.HasNextPage {
+			return
+		}
+		cursor = &conn.PageInfo.EndCursor
+	}
+}
+
+const projectV2FieldsQuery = `
+fields(first: 50) {
+	nodes {
+		... on ProjectV2FieldCommon { id name dataType }
+		... on ProjectV2SingleSelectField { options { name } }
+		... on ProjectV2IterationField { configuration { iterations { title startDate duration } } }
+	}
+}
+repositories(first: 50) { nodes { nameWithOwner } }
+`
+
+const projectV2ItemsQuery = `
+items(first: 100, after: $cursor) {
+	pageInfo { hasNextPage endCursor }
+	nodes {
+		id
+		type
+		content {
+			... on DraftIssue { title }
+			... on Issue { title url state repository { nameWithOwner } }
+			... on PullRequest { title url state repository { nameWithOwner } }
+		}
+		fieldValues(first: 50) {
+			nodes {
+				__typename
+				... on ProjectV2ItemFieldTextValue { text field { ... on ProjectV2FieldCommon { name } } }
+				... on ProjectV2ItemFieldNumberValue { number field { ... on ProjectV2FieldCommon { name } } }
+				... on ProjectV2ItemFieldDateValue { date field { ... on ProjectV2FieldCommon { name } } }
+				... on ProjectV2ItemFieldSingleSelectValue { name field { ... on ProjectV2FieldCommon { name } } }
+				... on ProjectV2ItemFieldIterationValue { title startDate field { ... on ProjectV2FieldCommon { name } } }
+			}
+		}
+	}
+}
+`
+
+// the raw graphql shapes that get flattened into the projectV2 types
+type rawProjectV2 struct {
+	ID               string
+	Number           int
+	Title            string
+	URL              string
+	Closed           bool
+	ShortDescription string
+
+	Fields struct {
+		Nodes []struct {
+			ID            string
+			Name          string
+			DataType      string
+			Options       []struct{ Name string }
+			Configuration struct {
+				Iterations []projectV2Iteration
+			}
+		}
+	}
+	Repositories struct {
+		Nodes []struct {
+			NameWithOwner string
+		}
+	}
+	Items rawProjectV2Items
+}
+
+type rawProjectV2Items struct {
+	PageInfo pageInfo
+	Nodes    []struct {
+		ID      string
+		Type    string
+		Content struct {
+			Title      string
+			URL        string
+			State      string
+			Repository struct {
+				NameWithOwner string
+			}
+		}
+		FieldValues struct {
+			Nodes []struct {
+				Typename  string `json:"__typename"`
+				Text      string
+				Number    float64
+				Date      string
+				Name      string
+				Title     string
+				StartDate string
+				Field     struct {
+					Name string
+				}
+			}
+		}
+	}
+}
+
+func queryProjectV2(org string, number int, shallow bool) projectV2 {
+	vars, items := "$org: String!, $number: Int!", ""
+	if !shallow {
+		vars, items = vars+", $cursor: String", projectV2ItemsQuery
+	}
+	query := fmt.Sprintf(`query(%s) {
+		organization(login: $org) {
+			projectV2(number: $number) {
+				id number title url closed shortDescription
+				%s
+				%s
+			}
+		}
+	}`, vars, projectV2FieldsQuery, items)
+
+	rsp := struct {
+		Organization struct {
+			ProjectV2 rawProjectV2
+		}
+	}{}
+	queryGraphQL(query, map[string]interface{}{"org": org, "number": number}, &rsp)
+	raw := rsp.Organization.ProjectV2
+	proj := projectV2{
+		ID:               raw.ID,
+		Number:           raw.Number,
+		Title:            raw.Title,
+		URL:              raw.URL,
+		Closed:           raw.Closed,
+		ShortDescription: raw.ShortDescription,
+	}
+
+	for _, f := range raw.Fields.Nodes {
+		if f.Name == "" {
+			continue
+		}
+		field := projectV2Field{
+			ID:         f.ID,
+			Name:       f.Name,
+			DataType:   f.DataType,
+			Iterations: f.Configuration.Iterations,
+		}
+		for _, o := range f.Options {
+			field.Options = append(field.Options, o.Name)
+		}
+		proj.ProjectFields = append(proj.ProjectFields, field)
+	}
+	for _, r := range raw.Repositories.Nodes {
+		proj.Repositories = append(proj.Repositories, r.NameWithOwner)
+	}
+	if shallow {
+		return proj
+	}
+
+	proj.Items = append(proj.Items, flattenProjectV2Items(raw.Items)...)
+	pages := raw.Items.PageInfo
+	for pages.HasNextPage {
+		next := struct {
+			Organization struct {
+				ProjectV2 struct {
+					Items rawProjectV2Items
+				}
+			}
+		}{}
+		queryGraphQL(fmt.Sprintf(`query($org: String!, $number: Int!, $cursor: String) {
+			organization(login: $org) {
+				projectV2(number: $number) { %s }
+			}
+		}`, projectV2ItemsQuery),
+			map[string]interface{}{"org": org, "number": number, "cursor": pages.EndCursor},
+			&next,
+		)
+		page := next.Organization.ProjectV2.Items
+		proj.Items = append(proj.Items, flattenProjectV2Items(page)...)
+		pages = page.PageInfo
+	}
+	log.Debug("loaded project items", zap.Int("number", number), zap.Int("items", len(proj.Items)))
+
+	return proj
+}
+
+func flattenProjectV2Items(raw rawProjectV2Items) []projectV2Item {
+	items := []projectV2Item{}
+	for _, n := range raw.Nodes {
+		item := projectV2Item{
+			ID:         n.ID,
+			Type:       n.Type,
+			Title:      n.Content.Title,
+			URL:        n.Content.URL,
+			State:      n.Content.State,
+			Repository: n.Content.Repository.NameWithOwner,
+			Values:     map[string]interface{}{},
+		}
+		for _, v := range n.FieldValues.Nodes {
+			if v.Field.Name == "" {
+				continue
+			}
+			switch v.Typename {
+			case "ProjectV2ItemFieldTextValue":
+				item.Values[v.Field.Name] = v.Text
+			case "ProjectV2ItemFieldNumberValue":
+				item.Values[v.Field.Name] = v.Number
+			case "ProjectV2ItemFieldDateValue":
+				item.Values[v.Field.Name] = v.Date
+			case "ProjectV2ItemFieldSingleSelectValue":
+				item.Values[v.Field.Name] = v.Name
+			case "ProjectV2ItemFieldIterationValue":
+				item.Values[v.Field.Name] = v.Title
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func migrateProjectV2Cmd() *cobra.Command {
+	var useDisk bool
+	var org, fieldName string
+	cmd := cobra.Command{
+		Use: "migrate-v2 [id]",
+		Run: func(cmd *cobra.Command, args []string) {
+			migrateProjectV2(loadProject(args[0], useDisk), org, fieldName)
+		},
+		Args: cobra.ExactArgs(1),
+	}
+	cmd.Flags().StringVar(&org, "org", "netlify", "the organization to create the new project in")
+	cmd.Flags().StringVar(&fieldName, "field", "Status", "the single select field that the columns become options of")
+	cmd.Flags().BoolVar(&useDisk, "disk", false, "if the provided project ID is a path to a json file")
+
+	return &cmd
+}
+
+type projectV2Ref struct {
+	ID     string
+	Number int
+	URL    string
+}
+
+type singleSelectField struct {
+	ID      string
+	Options []struct {
+		ID   string
+		Name string
+	}
+}
+
+func migrateProjectV2(proj project, org, fieldName string) {
+	dest := createProjectV2(org, proj.Name)
+	log.Info("created new project", zap.Int("number", dest.Number), zap.String("url", dest.URL))
+
+	colNames := []string{}
+	for _, col := range proj.Cols {
+		colNames = append(colNames, col.Name)
+	}
+	optionNames := uniqueOptionNames(colNames)
+	field := setupStatusField(dest.ID, fieldName, optionNames)
+	optionIDs := map[string]string{}
+	for _, o := range field.Options {
+		optionIDs[o.Name] = o.ID
+	}
+
+	var itemsCreated int
+	for i, col := range proj.Cols {
+		for _, card := range col.Cards {
+			itemID := addProjectV2Item(dest.ID, card)
+			setProjectV2ItemOption(dest.ID, itemID, field.ID, optionIDs[optionNames[i]])
+			if card.Archived {
+				archiveProjectV2Item(dest.ID, itemID)
+			}
+			log.Debug("created new item", zap.String("id", itemID), zap.String("column", col.Name), zap.Bool("archived", card.Archived))
+			itemsCreated++
+		}
+	}
+
+	log.Info("migrated the board to projects v2", zap.Int("items_created", itemsCreated))
+}
+
+// uniqueOptionNames numbers the columns that share a name, like "Todo (2)", so each
+// column gets its own option instead of the cards of both ending up in one
+func uniqueOptionNames(cols []string) []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, col := range cols {
+		name := col
+		for n := 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s (%d)", col, n)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func createProjectV2(org, title string) projectV2Ref {
+	owner := struct {
+		Organization struct {
+			ID string
+		}
+	}{}
+	queryGraphQL(`query($org: String!) { organization(login: $org) { id } }`,
+		map[string]interface{}{"org": org},
+		&owner,
+	)
+
+	created := struct {
+		CreateProjectV2 struct {
+			ProjectV2 projectV2Ref
+		}
+	}{}
+	queryGraphQL(`mutation($owner: ID!, $title: String!) {
+		createProjectV2(input: {ownerId: $owner, title: $title}) {
+			projectV2 { id number url }
+		}
+	}`,
+		map[string]interface{}{"owner": owner.Organization.ID, "title": title},
+		&created,
+	)
+	return created.CreateProjectV2.ProjectV2
+}
+
+// setupStatusField makes the single select field's options match the columns. New
+// projects come with a Status field already, so that is updated if it exists.
+func setupStatusField(projectID, name string, options []string) singleSelectField {
+	selectOptions := []map[string]string{}
+	for _, o := range options {
+		selectOptions = append(selectOptions, map[string]string{
+			"name":        o,
+			"color":       "GRAY",
+			"description": "",
+		})
+	}
+
+	existing := struct {
+		Node struct {
+			Field *struct {
+				ID string
+			}
+		}
+	}{}
+	queryGraphQL(`query($project: ID!, $name: String!) {
+		node(id: $project) {
+			... on ProjectV2 {
+				field(name: $name) { ... on ProjectV2SingleSelectField { id } }
+			}
+		}
+	}`,
+		map[string]interface{}{"project": projectID, "name": name},
+		&existing,
+	)
+
+	if existing.Node.Field != nil && existing.Node.Field.ID != "" {
+		updated := struct {
+			UpdateProjectV2Field struct {
+				ProjectV2Field singleSelectField
+			}
+		}{}
+		queryGraphQL(`mutation($field: ID!, $options: [ProjectV2SingleSelectFieldOptionInput!]) {
+			updateProjectV2Field(input: {fieldId: $field, singleSelectOptions: $options}) {
+				projectV2Field { ... on ProjectV2SingleSelectField { id options { id name } } }
+			}
+		}`,
+			map[string]interface{}{"field": existing.Node.Field.ID, "options": selectOptions},
+			&updated,
+		)
+		return updated.UpdateProjectV2Field.ProjectV2Field
+	}
+
+	created := struct {
+		CreateProjectV2Field struct {
+			ProjectV2Field singleSelectField
+		}
+	}{}
+	queryGraphQL(`mutation($project: ID!, $name: String!, $options: [ProjectV2SingleSelectFieldOptionInput!]) {
+		createProjectV2Field(input: {projectId: $project, dataType: SINGLE_SELECT, name: $name, singleSelectOptions: $options}) {
+			projectV2Field { ... on ProjectV2SingleSelectField { id options { id name } } }
+		}
+	}`,
+		map[string]interface{}{"project": projectID, "name": name, "options": selectOptions},
+		&created,
+	)
+	return created.CreateProjectV2Field.ProjectV2Field
+}
+
+// addProjectV2Item adds the issue or PR behind the card to the project, note cards
+// become draft issues.
+func addProjectV2Item(projectID string, card projectCard) string {
+	if card.Note != "" {
+		title, body := splitNote(card.Note)
+		added := struct {
+			AddProjectV2DraftIssue struct {
+				ProjectItem struct {
+					ID string
+				}
+			}
+		}{}
+		queryGraphQL(`mutation($project: ID!, $title: String!, $body: String) {
+			addProjectV2DraftIssue(input: {projectId: $project, title: $title, body: $body}) {
+				projectItem { id }
+			}
+		}`,
+			map[string]interface{}{"project": projectID, "title": title, "body": body},
+			&added,
+		)
+		return added.AddProjectV2DraftIssue.ProjectItem.ID
+	}
+
+	code, raw := queryGitHub(card.ContentURL)
+	requireCode(http.StatusOK, code, raw)
+	content := struct {
+		NodeID string `json:"node_id"`
+	}{}
+	panicOnErr(json.Unmarshal(raw, &content))
+
+	added := struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID string
+			}
+		} `json:"addProjectV2ItemById"`
+	}{}
+	queryGraphQL(`mutation($project: ID!, $content: ID!) {
+		addProjectV2ItemById(input: {projectId: $project, contentId: $content}) {
+			item { id }
+		}
+	}`,
+		map[string]interface{}{"project": projectID, "content": content.NodeID},
+		&added,
+	)
+	return added.AddProjectV2ItemByID.Item.ID
+}
+
+func setProjectV2ItemOption(projectID, itemID, fieldID, optionID string) {
+	queryGraphQL(`mutation($project: ID!, $item: ID!, $field: ID!, $option: String!) {
+		updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: {singleSelectOptionId: $option}}) {
+			projectV2Item { id }
+		}
+	}`,
+		map[string]interface{}{"project": projectID, "item": itemID, "field": fieldID, "option": optionID},
+		nil,
+	)
+}
+
+func archiveProjectV2Item(projectID, itemID string) {
+	queryGraphQL(`mutation($project: ID!, $item: ID!) {
+		archiveProjectV2Item(input: {projectId: $project, itemId: $item}) {
+			item { id }
+		}
+	}`,
+		map[string]interface{}{"project": projectID, "item": itemID},
+		nil,
+	)
+}
+
+// splitNote uses the first line of the note as the title and the whole note as the body
+func splitNote(note string) (string, string) {
+	title := strings.TrimSpace(strings.SplitN(strings.TrimSpace(note), "\n", 2)[0])
+	title = strings.TrimLeft(title, "#* ")
+	// the limit is in characters, cutting the bytes could split one in half
+	if runes := []rune(title); len(runes) > 256 {
+		title = string(runes[:256])
+	}
+	if title == "" {
+		title = "Untitled note"
+	}
+	return title, note
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestUniqueOptionNames(t *testing.T) {
+	tests := []struct {
+		cols     []string
+		expected []string
+	}{
+		{[]string{"Todo", "Done"}, []string{"Todo", "Done"}},
+		{[]string{"Todo", "Todo", "Todo"}, []string{"Todo", "Todo (2)", "Todo (3)"}},
+		// a column already named like a numbered one doesn't get merged either
+		{[]string{"Todo", "Todo (2)", "Todo"}, []string{"Todo", "Todo (2)", "Todo (3)"}},
+	}
+	for _, tc := range tests {
+		if actual := uniqueOptionNames(tc.cols); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("uniqueOptionNames(%q) = %q, expected %q", tc.cols, actual, tc.expected)
+		}
+	}
+}
+
+func TestSplitNote(t *testing.T) {
+	tests := []struct {
+		note  string
+		title string
+	}{
+		{"# Heading\nthe rest", "Heading"},
+		{"  * a list item  ", "a list item"},
+		{"", "Untitled note"},
+		{strings.Repeat("é", 300), strings.Repeat("é", 256)},
+	}
+	for _, tc := range tests {
+		title, body := splitNote(tc.note)
+		if title != tc.title || body != tc.note {
+			t.Errorf("splitNote(%q) = %q %q, expected %q %q", tc.note, title, body, tc.title, tc.note)
+		}
+		if !utf8.ValidString(title) {
+			t.Errorf("splitNote(%q) made an invalid title %q", tc.note, title)
+		}
+	}
+}
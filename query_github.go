@@ -1,29 +1,234 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
 func queryGitHubCmd() *cobra.Command {
-	var acceptRaw bool
+	var acceptRaw, include, paginate, stream bool
+	var method, data, dataFile string
+	var headers []string
 	cmd := cobra.Command{
 		Use:  "query-github",
 		Args: cobra.ExactArgs(1),
+		// the response is written as is unless it goes through the encoder, so
+		// there's nothing for the encoder to finish
+		PreRun: func(cmd *cobra.Command, args []string) {
+			if !encodeResponse() {
+				encFinish = nil
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			path := args[0]
 			var opts []opt
 			if acceptRaw {
 				opts = []opt{withAccept("application/vnd.github.v3.raw")}
 			}
-			status, raw := queryGitHub(path, opts...)
+			for _, h := range headers {
+				parts := strings.SplitN(h, ":", 2)
+				if len(parts) != 2 {
+					panic(fmt.Sprintf("expected the header to be 'Key: value': %s", h))
+				}
+				opts = append(opts, withHeader(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])))
+			}
+
+			if dataFile != "" {
+				data = "@" + dataFile
+			}
+			if data != "" {
+				opts = append(opts, withPayload(readData(data)))
+				// like curl, sending data switches to a POST unless the method is set
+				if !cmd.Flags().Changed("method") {
+					method = http.MethodPost
+				}
+			}
+			opts = append(opts, withMethod(strings.ToUpper(method)))
+
+			if paginate {
+				if include {
+					panic("--include can't be used with --paginate")
+				}
+				queryAllPages(path, stream, opts...)
+				return
+			}
+
+			status, rspHeaders, raw := queryGitHubResponse(path, opts...)
 			log.Info("finished querying github", zap.Int("status", status))
-			fmt.Println(string(raw))
+			if include {
+				writeResponseHead(out, status, rspHeaders)
+			}
+			if encodeResponse() {
+				items, _ := pageItems(raw)
+				encodeItems(items)
+				return
+			}
+			fmt.Fprintln(out, string(raw))
 		},
 	}
 	cmd.Flags().BoolVar(&acceptRaw, "raw", false, "if we should use the raw accept header")
+	cmd.Flags().StringVarP(&method, "method", "X", http.MethodGet, "the http method to use")
+	cmd.Flags().StringVar(&data, "data", "", "the body to send, use @file to read it from a file or @- for stdin")
+	cmd.Flags().StringVar(&dataFile, "data-file", "", "a file to read the body from, use - for stdin")
+	cmd.Flags().StringArrayVarP(&headers, "header", "H", nil, "an extra header to send as 'Key: value', can be repeated")
+	cmd.Flags().BoolVar(&include, "include", false, "print the response status and headers")
+	cmd.Flags().BoolVar(&paginate, "paginate", false, "fetch every page and merge the results into a single array")
+	cmd.Flags().BoolVar(&stream, "stream", false, "with --paginate, write each item on its own line as they are fetched")
 
 	return &cmd
 }
+
+// readData handles the @file and @- forms of the data flag
+func readData(data string) []byte {
+	if !strings.HasPrefix(data, "@") {
+		return []byte(data)
+	}
+	file := strings.TrimPrefix(data, "@")
+	var bs []byte
+	var err error
+	if file == "-" {
+		bs, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		bs, err = ioutil.ReadFile(file)
+	}
+	panicOnErr(err)
+	return bs
+}
+
+func writeResponseHead(w io.Writer, status int, headers http.Header) {
+	fmt.Fprintf(w, "HTTP %d %s\n", status, http.StatusText(status))
+	keys := []string{}
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range headers[k] {
+			fmt.Fprintf(w, "%s: %s\n", k, v)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// pageItems pulls the items out of a page, list endpoints return an array and the
+// search endpoints nest the array under items. Anything else is a single item from
+// an endpoint that isn't paginated.
+func pageItems(raw []byte) ([]json.RawMessage, bool) {
+	items := []json.RawMessage{}
+	trimmed := bytes.TrimSpace(raw)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		panicOnErr(json.Unmarshal(trimmed, &items))
+		return items, true
+	}
+
+	search := struct {
+		Items *[]json.RawMessage
+	}{}
+	if err := json.Unmarshal(trimmed, &search); err == nil && search.Items != nil {
+		return *search.Items, true
+	}
+	return []json.RawMessage{trimmed}, false
+}
+
+// queryAllPages follows the next links github sends until there aren't any more.
+// An error on the first page fails, but search stops with a 422 past its 1000
+// results, so that ends the paging with what was already fetched.
+func queryAllPages(path string, stream bool, opts ...opt) {
+	all := []json.RawMessage{}
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	next := fmt.Sprintf("%s%sper_page=100", path, sep)
+	var prev []byte
+	for page := 1; next != ""; page++ {
+		code, headers, raw := queryGitHubResponse(next, opts...)
+		if code == http.StatusUnprocessableEntity && page > 1 {
+			log.Warn("github refused the next page, stopping with what was fetched", zap.Int("page", page), zap.String("response", string(raw)))
+			break
+		}
+		if code != http.StatusOK {
+			panic(fmt.Sprintf("got a %d in response to %s: %s", code, next, string(raw)))
+		}
+		// some endpoints ignore the paging and send the same page every time
+		if bytes.Equal(raw, prev) {
+			log.Warn("got the same page twice, stopping", zap.Int("page", page))
+			break
+		}
+		prev = raw
+		log.Debug("fetched a new page", zap.Int("page", page))
+
+		items, _ := pageItems(raw)
+		if encodeResponse() {
+			encodeItems(items)
+		} else if stream {
+			for _, item := range items {
+				var line bytes.Buffer
+				panicOnErr(json.Compact(&line, item))
+				fmt.Fprintln(out, line.String())
+			}
+		} else {
+			all = append(all, items...)
+		}
+		next = nextPageLink(headers)
+	}
+	log.Debug("finished paging", zap.Int("items", len(all)))
+
+	if stream || encodeResponse() {
+		return
+	}
+	var bs []byte
+	var err error
+	if pretty {
+		bs, err = json.MarshalIndent(all, "", "  ")
+	} else {
+		bs, err = json.Marshal(all)
+	}
+	panicOnErr(err)
+	fmt.Fprintln(out, string(bs))
+}
+
+var linkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="([^"]+)"`)
+
+// nextPageLink is the url of the next page from the Link header, or "" on the last page
+func nextPageLink(headers http.Header) string {
+	for _, match := range linkPattern.FindAllStringSubmatch(headers.Get("Link"), -1) {
+		if match[2] == "next" {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// encodeResponse is if the response has to go through the encoder, otherwise it's
+// written as the json github sent
+func encodeResponse() bool {
+	if len(selectPaths) > 0 || len(filterExprs) > 0 || useCSV {
+		return true
+	}
+	switch format {
+	case formatYAML, formatCSV, formatTSV, formatMarkdown, formatHTML:
+		return true
+	}
+	return false
+}
+
+// encodeItems sends the items through the encoder so they can be selected and filtered
+func encodeItems(items []json.RawMessage) {
+	for _, item := range items {
+		var obj interface{}
+		panicOnErr(json.Unmarshal(item, &obj))
+		panicOnErr(enc(obj))
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNextPageLink(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"", ""},
+		{`<https://api.github.com/user/repos?page=3&per_page=100>; rel="next", <https://api.github.com/user/repos?page=50&per_page=100>; rel="last"`, "https://api.github.com/user/repos?page=3&per_page=100"},
+		{`<https://api.github.com/users?since=135>; rel="next"`, "https://api.github.com/users?since=135"},
+		{`<https://api.github.com/user/repos?page=1>; rel="prev", <https://api.github.com/user/repos?page=1>; rel="first"`, ""},
+	}
+	for _, tc := range tests {
+		headers := http.Header{}
+		if tc.link != "" {
+			headers.Set("Link", tc.link)
+		}
+		if actual := nextPageLink(headers); actual != tc.expected {
+			t.Errorf("nextPageLink(%q) = %q, expected %q", tc.link, actual, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func queryGraphQLCmd() *cobra.Command {
+	var vars, rawVars []string
+	var paginate bool
+	cmd := cobra.Command{
+		Use:  "query-graphql <query>",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			query := string(readData(args[0]))
+			variables := map[string]interface{}{}
+			for _, v := range vars {
+				key, val := splitGraphQLVar(v)
+				variables[key] = parseGraphQLVar(val)
+			}
+			for _, v := range rawVars {
+				key, val := splitGraphQLVar(v)
+				variables[key] = val
+			}
+
+			if !paginate {
+				var data interface{}
+				queryGraphQL(query, variables, &data)
+				panicOnErr(enc(data))
+				return
+			}
+			panicOnErr(enc(queryGraphQLPages(query, variables)))
+		},
+	}
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "a variable for the query as key=value, values that are valid json are sent as json")
+	cmd.Flags().StringArrayVar(&rawVars, "raw-var", nil, "a variable for the query as key=value that's always sent as a string, even if it looks like json")
+	cmd.Flags().BoolVar(&paginate, "paginate", false, "follow the first connection with pageInfo { hasNextPage endCursor } using the $endCursor variable")
+
+	return &cmd
+}
+
+func splitGraphQLVar(v string) (string, string) {
+	parts := strings.SplitN(v, "=", 2)
+	if len(parts) != 2 {
+		panic(fmt.Sprintf("expected the variable to be key=value: %s", v))
+	}
+	return parts[0], parts[1]
+}
+
+// parseGraphQLVar sends numbers, booleans, null, arrays and objects as json, anything else as a string
+func parseGraphQLVar(val string) interface{} {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(val), &parsed); err == nil {
+		return parsed
+	}
+	return val
+}
+
+// queryGraphQLPages runs the query until the first connection that has a pageInfo
+// runs out of pages, merging the nodes and edges of each page into the first one
+func queryGraphQLPages(query string, variables map[string]interface{}) interface{} {
+	if !strings.Contains(query, "$endCursor") {
+		panic("a paginated query must take an $endCursor variable and pass it as the after argument")
+	}
+
+	var result interface{}
+	queryGraphQL(query, variables, &result)
+	path, conn := findConnection(result, nil)
+	if conn == nil {
+		log.Info("didn't find a connection with a pageInfo, there is nothing to paginate")
+		return result
+	}
+	log.Debug("found the connection to paginate", zap.String("path", strings.Join(path, ".")))
+
+	pages := 1
+	for {
+		info, _ := conn["pageInfo"].(map[string]interface{})
+		hasNext, _ := info["hasNextPage"].(bool)
+		cursor, _ := info["endCursor"].(string)
+		if !hasNext || cursor == "" {
+			break
+		}
+
+		variables["endCursor"] = cursor
+		var page interface{}
+		queryGraphQL(query, variables, &page)
+		pages++
+
+		next := followPath(page, path)
+		if next == nil {
+			panic(fmt.Sprintf("the connection at %s is missing from page %d", strings.Join(path, "."), pages))
+		}
+		for _, key := range []string{"nodes", "edges"} {
+			if items, ok := next[key].([]interface{}); ok {
+				existing, _ := conn[key].([]interface{})
+				conn[key] = append(existing, items...)
+			}
+		}
+		conn["pageInfo"] = next["pageInfo"]
+	}
+	log.Debug("finished paging", zap.Int("pages", pages))
+	return result
+}
+
+// findConnection walks the response for the first object with a pageInfo
+func findConnection(val interface{}, path []string) ([]string, map[string]interface{}) {
+	obj, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := obj["pageInfo"]; ok {
+		return path, obj
+	}
+
+	keys := []string{}
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		childPath := append(append([]string{}, path...), k)
+		if p, conn := findConnection(obj[k], childPath); conn != nil {
+			return p, conn
+		}
+	}
+	return nil, nil
+}
+
+func followPath(val interface{}, path []string) map[string]interface{} {
+	for _, p := range path {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		val = obj[p]
+	}
+	obj, _ := val.(map[string]interface{})
+	return obj
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatHTML     = "html"
+)
+
+// reportTable buffers the rows of a report, the headers come from the first row
+type reportTable struct {
+	headers []string
+	rows    [][]interface{}
+}
+
+func (t *reportTable) add(obj interface{}) error {
+	rows, err := tableRows(obj)
+	if err != nil {
+		return err
+	}
+	for _, fields := range rows {
+		if t.headers == nil {
+			for _, f := range fields {
+				t.headers = append(t.headers, f.header)
+			}
+		}
+		row := []interface{}{}
+		for _, f := range fields {
+			row = append(row, f.value)
+		}
+		t.rows = append(t.rows, row)
+	}
+	return nil
+}
+
+// reset drops the rows that were written so the next table only has the new ones
+func (t *reportTable) reset() {
+	t.headers = nil
+	t.rows = nil
+}
+
+// flushReport writes the markdown or html table of what's been encoded so far, for
+// the commands that ask the user something or keep running instead of finishing
+func flushReport() {
+	if (format == formatMarkdown || format == formatHTML) && encFinish != nil {
+		panicOnErr(encFinish())
+	}
+}
+
+// checkMark is how booleans are shown in the reports, like the columns of a repoStatus
+func checkMark(val bool) string {
+	if val {
+		return "✅"
+	}
+	return "❌"
+}
+
+// buildMarkdownEncoder writes a github flavored markdown table once all the rows are in,
+// or of the rows since the last time it was flushed
+func buildMarkdownEncoder(out io.WriteCloser) (encoder, finisher) {
+	table := &reportTable{}
+	finish := func() error {
+		if table.headers == nil {
+			return nil
+		}
+		var buf strings.Builder
+		writeMarkdownRow(&buf, table.headers)
+		seps := []string{}
+		for range table.headers {
+			seps = append(seps, "---")
+		}
+		writeMarkdownRow(&buf, seps)
+		for _, row := range table.rows {
+			cells := []string{}
+			for _, val := range row {
+				cells = append(cells, markdownCell(val))
+			}
+			writeMarkdownRow(&buf, cells)
+		}
+		table.reset()
+		_, err := io.WriteString(out, buf.String())
+		return err
+	}
+	return table.add, finish
+}
+
+func writeMarkdownRow(buf *strings.Builder, cells []string) {
+	buf.WriteString("| ")
+	buf.WriteString(strings.Join(cells, " | "))
+	buf.WriteString(" |\n")
+}
+
+func markdownCell(val interface{}) string {
+	if b, ok := val.(bool); ok {
+		return checkMark(b)
+	}
+	cell := csvValue(val)
+	cell = strings.ReplaceAll(cell, "|", `\|`)
+	cell = strings.ReplaceAll(cell, "\r\n", "<br>")
+	return strings.ReplaceAll(cell, "\n", "<br>")
+}
+
+type htmlCell struct {
+	Text  string
+	Class string
+}
+
+// buildHTMLEncoder writes a page with everything it needs inline, the table sorts
+// on the column that's clicked
+func buildHTMLEncoder(out io.WriteCloser) (encoder, finisher) {
+	table := &reportTable{}
+	finish := func() error {
+		if table.headers == nil {
+			return nil
+		}
+		rows := [][]htmlCell{}
+		for _, row := range table.rows {
+			cells := []htmlCell{}
+			for _, val := range row {
+				cells = append(cells, htmlReportCell(val))
+			}
+			rows = append(rows, cells)
+		}
+		headers := table.headers
+		table.reset()
+		return htmlReport.Execute(out, map[string]interface{}{
+			"Headers": headers,
+			"Rows":    rows,
+		})
+	}
+	return table.add, finish
+}
+
+func htmlReportCell(val interface{}) htmlCell {
+	if b, ok := val.(bool); ok {
+		if b {
+			return htmlCell{Text: checkMark(b), Class: "pass"}
+		}
+		return htmlCell{Text: checkMark(b), Class: "fail"}
+	}
+	return htmlCell{Text: csvValue(val)}
+}
+
+var htmlReport = template.Must(template.New("report").Parse(fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>report</title>
+<style>
+%s
+</style>
+</head>
+<body>
+<table>
+<thead>
+<tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td{{if .Class}} class="{{.Class}}"{{end}}>{{.Text}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+<script>
+%s
+</script>
+</body>
+</html>
+`, htmlReportStyle, htmlReportScript)))
+
+const htmlReportStyle = `body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #d0d7de; padding: 4px 8px; text-align: left; white-space: pre-wrap; }
+th { background: #f6f8fa; cursor: pointer; user-select: none; }
+th.asc::after { content: " ▲"; }
+th.desc::after { content: " ▼"; }
+td.pass { background: #dafbe1; text-align: center; }
+td.fail { background: #ffebe9; text-align: center; }`
+
+// sorts numerically when both cells are numbers, otherwise as text
+const htmlReportScript = `document.querySelectorAll("th").forEach(function (th, idx) {
+  th.addEventListener("click", function () {
+    var tbody = th.closest("table").tBodies[0];
+    var asc = !th.classList.contains("asc");
+    th.parentNode.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var rows = Array.prototype.slice.call(tbody.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[idx].textContent, y = b.cells[idx].textContent;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+      return asc ? cmp : -cmp;
+    });
+    rows.forEach(function (r) { tbody.appendChild(r); });
+  });
+});`
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error {
+	return nil
+}
+
+func TestMarkdownFlush(t *testing.T) {
+	buf := &bufferCloser{}
+	write, finish := buildMarkdownEncoder(buf)
+
+	if err := write(syncChange{Action: syncAddColumn, Column: "todo"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := finish(); err != nil {
+		t.Fatal(err)
+	}
+	first := "| action | column | card | position |\n| --- | --- | --- | --- |\n| add_column | todo |  |  |\n"
+	if buf.String() != first {
+		t.Errorf("the first flush wrote %q, expected %q", buf.String(), first)
+	}
+
+	// a flush only has the rows since the last one, and nothing if there aren't any
+	buf.Reset()
+	if err := write(syncChange{Action: syncRemoveColumn, Column: "old"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := finish(); err != nil {
+		t.Fatal(err)
+	}
+	second := "| action | column | card | position |\n| --- | --- | --- | --- |\n| remove_column | old |  |  |\n"
+	if buf.String() != second {
+		t.Errorf("the second flush wrote %q, expected %q", buf.String(), second)
+	}
+
+	buf.Reset()
+	if err := finish(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("an empty flush wrote %q", buf.String())
+	}
+}
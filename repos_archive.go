@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const defaultArchiveNote = "> **ARCHIVED**: This repository is no longer maintained and has been archived."
+
+type archiveCriteria struct {
+	staleDays    int
+	noOpenPRs    bool
+	noOpenIssues bool
+	nameGlob     string
+	topics       []string
+}
+
+func (c archiveCriteria) empty() bool {
+	return c.staleDays == 0 && !c.noOpenPRs && !c.noOpenIssues && c.nameGlob == "" && len(c.topics) == 0
+}
+
+func archiveReposCmd() *cobra.Command {
+	var criteria archiveCriteria
+	var yes, addNote bool
+	var note string
+	cmd := cobra.Command{
+		Use: "archive",
+		Run: func(cmd *cobra.Command, args []string) {
+			if criteria.empty() {
+				panic("must provide at least one criteria to select repos by")
+			}
+			candidates := findArchiveCandidates(criteria)
+			if len(candidates) == 0 {
+				log.Info("no repos matched the criteria")
+				return
+			}
+			// the candidates have to be shown before asking about them
+			flushReport()
+			if !yes && !confirm(fmt.Sprintf("archive %d repos?", len(candidates))) {
+				log.Info("not archiving anything")
+				return
+			}
+			for _, c := range candidates {
+				if addNote {
+					addReadmeNote(c.Repo, note)
+				}
+				setArchived(c.Repo, true)
+			}
+		},
+	}
+	cmd.Flags().IntVar(&criteria.staleDays, "stale-days", 0, "select repos that haven't been pushed to in this many days")
+	cmd.Flags().BoolVar(&criteria.noOpenPRs, "no-open-prs", false, "select repos without any open pull requests")
+	cmd.Flags().BoolVar(&criteria.noOpenIssues, "no-open-issues", false, "select repos without any open issues")
+	cmd.Flags().StringVar(&criteria.nameGlob, "name", "", "select repos with a name matching this glob")
+	cmd.Flags().StringSliceVar(&criteria.topics, "topic", nil, "select repos with any of these topics")
+	cmd.Flags().BoolVar(&yes, "yes", false, "archive the candidates without asking")
+	cmd.Flags().BoolVar(&addNote, "readme-note", false, "add a note to the README before archiving")
+	cmd.Flags().StringVar(&note, "note", defaultArchiveNote, "the note to add to the README")
+	addRepoFilterFlags(&cmd)
+
+	return &cmd
+}
+
+func unarchiveReposCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use: "unarchive <repo>...",
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, r := range args {
+				setArchived(qualifyRepo(r), false)
+			}
+		},
+		Args: cobra.MinimumNArgs(1),
+	}
+	return &cmd
+}
+
+type archiveCandidate struct {
+	Repo       string
+	PushedAt   time.Time `json:"pushed_at"`
+	OpenPRs    int       `json:"open_prs"`
+	OpenIssues int       `json:"open_issues"`
+	Reasons    []string
+}
+
+func (c archiveCandidate) Fields() []csvField {
+	return []csvField{
+		{"name", c.Repo},
+		{"last push", c.PushedAt},
+		{"open prs", c.OpenPRs},
+		{"open issues", c.OpenIssues},
+		{"reasons", strings.Join(c.Reasons, ",")},
+	}
+}
+
+func findArchiveCandidates(criteria archiveCriteria) []archiveCandidate {
+	candidates := []archiveCandidate{}
+	readRepoPages(func(r repo) error {
+		if r.Archived {
+			return nil
+		}
+		c := archiveCandidate{
+			Repo:     r.Name,
+			PushedAt: r.PushedAt,
+		}
+
+		// check the cheap criteria first so we only count PRs when we have to
+		if criteria.nameGlob != "" {
+			name := strings.TrimPrefix(r.Name, r.Owner.Login+"/")
+			matched, err := path.Match(criteria.nameGlob, name)
+			panicOnErr(err)
+			if !matched {
+				return nil
+			}
+			c.Reasons = append(c.Reasons, fmt.Sprintf("name matches %s", criteria.nameGlob))
+		}
+		if len(criteria.topics) > 0 {
+			topic := firstMatchingTopic(r.Topics, criteria.topics)
+			if topic == "" {
+				return nil
+			}
+			c.Reasons = append(c.Reasons, fmt.Sprintf("has topic %s", topic))
+		}
+		if criteria.staleDays > 0 {
+			age := time.Since(r.PushedAt)
+			if age < time.Duration(criteria.staleDays)*24*time.Hour {
+				return nil
+			}
+			c.Reasons = append(c.Reasons, fmt.Sprintf("not pushed to in %d days", int(age.Hours()/24)))
+		}
+
+		if criteria.noOpenPRs || criteria.noOpenIssues {
+			c.OpenPRs = countOpenPRs(r.Name)
+			// the open issue count from github includes the PRs
+			c.OpenIssues = r.OpenIssuesCount - c.OpenPRs
+		}
+		if criteria.noOpenPRs {
+			if c.OpenPRs > 0 {
+				return nil
+			}
+			c.Reasons = append(c.Reasons, "no open PRs")
+		}
+		if criteria.noOpenIssues {
+			if c.OpenIssues > 0 {
+				return nil
+			}
+			c.Reasons = append(c.Reasons, "no open issues")
+		}
+
+		candidates = append(candidates, c)
+		return enc(c)
+	})
+	return candidates
+}
+
+func firstMatchingTopic(have, want []string) string {
+	for _, w := range want {
+		for _, h := range have {
+			if strings.EqualFold(w, h) {
+				return h
+			}
+		}
+	}
+	return ""
+}
+
+func countOpenPRs(repoName string) int {
+	count := 0
+	queryByPage(fmt.Sprintf("/repos/%s/pulls", repoName), func(raw []byte) bool {
+		prs := []struct {
+			ID int
+		}{}
+		panicOnErr(json.Unmarshal(raw, &prs))
+		count += len(prs)
+		return len(prs) != 0
+	})
+	return count
+}
+
+func setArchived(repoName string, archived bool) {
+	body, err := json.Marshal(&struct {
+		Archived bool `json:"archived"`
+	}{
+		Archived: archived,
+	})
+	panicOnErr(err)
+
+	code, raw := queryGitHub(fmt.Sprintf("/repos/%s", repoName),
+		withMethod(http.MethodPatch),
+		withPayload(body),
+	)
+	requireCode(http.StatusOK, code, raw)
+	log.Info("updated repo", zap.String("repo", repoName), zap.Bool("archived", archived))
+}
+
+// addReadmeNote puts the note at the top of the README, it has to happen before
+// archiving because the repo is read only after that.
+func addReadmeNote(repoName, note string) {
+	code, raw := queryGitHub(fmt.Sprintf("/repos/%s/readme", repoName))
+	if code == http.StatusNotFound {
+		log.Info("no README to add the note to", zap.String("repo", repoName))
+		return
+	}
+	requireCode(http.StatusOK, code, raw)
+	var readme fileEntry
+	panicOnErr(json.Unmarshal(raw, &readme))
+
+	content := append([]byte(note+"\n\n"), readme.Contents()...)
+	body, err := json.Marshal(&struct {
+		Message string `json:"message"`
+		Content string `json:"content"`
+		SHA     string `json:"sha"`
+	}{
+		Message: "Add archived note to the README",
+		Content: base64.StdEncoding.EncodeToString(content),
+		SHA:     readme.SHA,
+	})
+	panicOnErr(err)
+
+	code, raw = queryGitHub(fmt.Sprintf("/repos/%s/contents/%s", repoName, readme.Path),
+		withMethod(http.MethodPut),
+		withPayload(body),
+	)
+	requireCode(http.StatusOK, code, raw)
+	log.Debug("added the archived note to the README", zap.String("repo", repoName))
+}
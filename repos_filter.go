@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const filterDateFormat = "2006-01-02"
+
+// repoFilter is applied to every repo that readRepoPages walks. The type, sort and
+// direction are handed to github, the rest are checked locally.
+type repoFilter struct {
+	languages     []string
+	topics        []string
+	visibility    string
+	forks         string
+	pushedAfter   string
+	pushedBefore  string
+	createdAfter  string
+	createdBefore string
+	minSize       int
+	maxSize       int
+	nameRegex     string
+	defaultBranch string
+
+	repoType  string
+	sort      string
+	direction string
+
+	nameMatcher *regexp.Regexp
+	dates       map[string]time.Time
+}
+
+var repoFilters repoFilter
+
+// addRepoFilterFlags adds the filters to a command that walks the repos with
+// readRepoPages. A flag the command already has keeps the command's meaning.
+func addRepoFilterFlags(cmd *cobra.Command) {
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	flags.StringSliceVar(&repoFilters.languages, "language", nil, "only process repos in these languages")
+	flags.StringSliceVar(&repoFilters.topics, "topic", nil, "only process repos with any of these topics")
+	flags.StringVar(&repoFilters.visibility, "visibility", "", "only process repos with this visibility: public, private or internal")
+	flags.StringVar(&repoFilters.forks, "forks", "include", "how to handle forked repos: include, exclude or only")
+	flags.StringVar(&repoFilters.pushedAfter, "pushed-after", "", "only process repos pushed to after this date (YYYY-MM-DD)")
+	flags.StringVar(&repoFilters.pushedBefore, "pushed-before", "", "only process repos last pushed to before this date (YYYY-MM-DD)")
+	flags.StringVar(&repoFilters.createdAfter, "created-after", "", "only process repos created after this date (YYYY-MM-DD)")
+	flags.StringVar(&repoFilters.createdBefore, "created-before", "", "only process repos created before this date (YYYY-MM-DD)")
+	flags.IntVar(&repoFilters.minSize, "min-size", 0, "only process repos at least this size in KB")
+	flags.IntVar(&repoFilters.maxSize, "max-size", 0, "only process repos at most this size in KB")
+	flags.StringVar(&repoFilters.nameRegex, "name-regex", "", "only process repos with a name matching this regex")
+	flags.StringVar(&repoFilters.defaultBranch, "default-branch", "", "only process repos with this default branch")
+	flags.StringVar(&repoFilters.repoType, "type", "", "the type of repos to ask github for: all, public, private, forks, sources or member")
+	flags.StringVar(&repoFilters.sort, "sort", "", "how github should sort the repos: created, updated, pushed or full_name")
+	flags.StringVar(&repoFilters.direction, "direction", "", "the sort direction: asc or desc")
+
+	flags.VisitAll(func(f *pflag.Flag) {
+		if cmd.Flags().Lookup(f.Name) == nil {
+			cmd.Flags().AddFlag(f)
+		}
+	})
+}
+
+// query returns the server side filtering github supports on the org repo listing
+func (f repoFilter) query() string {
+	vals := url.Values{}
+	if f.repoType != "" {
+		vals.Set("type", f.repoType)
+	}
+	if f.sort != "" {
+		vals.Set("sort", f.sort)
+	}
+	if f.direction != "" {
+		vals.Set("direction", f.direction)
+	}
+	return vals.Encode()
+}
+
+// prepare validates and parses the filter values, it has to be called before matches
+func (f *repoFilter) prepare() {
+	switch f.forks {
+	case "", "include", "exclude", "only":
+	default:
+		panic(fmt.Sprintf("unexpected value for forks: %s", f.forks))
+	}
+
+	if f.nameRegex != "" {
+		f.nameMatcher = regexp.MustCompile(f.nameRegex)
+	}
+
+	f.dates = map[string]time.Time{}
+	for name, val := range map[string]string{
+		"pushed-after":   f.pushedAfter,
+		"pushed-before":  f.pushedBefore,
+		"created-after":  f.createdAfter,
+		"created-before": f.createdBefore,
+	} {
+		if val == "" {
+			continue
+		}
+		ts, err := time.Parse(filterDateFormat, val)
+		if err != nil {
+			panic(fmt.Sprintf("failed to parse %s, expected a date like %s: %s", name, filterDateFormat, val))
+		}
+		f.dates[name] = ts
+	}
+}
+
+// matches returns the reason the repo was filtered out, or "" if it should be processed
+func (f repoFilter) matches(r repo) string {
+	if len(f.languages) > 0 {
+		lang, _ := r.Language.(string)
+		if !containsFold(f.languages, lang) {
+			return "language"
+		}
+	}
+	if len(f.topics) > 0 && firstMatchingTopic(r.Topics, f.topics) == "" {
+		return "topic"
+	}
+	if f.visibility != "" && !strings.EqualFold(f.visibility, r.visibility()) {
+		return "visibility"
+	}
+	if (f.forks == "exclude" && r.Fork) || (f.forks == "only" && !r.Fork) {
+		return "fork"
+	}
+	if ts, ok := f.dates["pushed-after"]; ok && r.PushedAt.Before(ts) {
+		return "pushed-after"
+	}
+	if ts, ok := f.dates["pushed-before"]; ok && !r.PushedAt.Before(ts) {
+		return "pushed-before"
+	}
+	if ts, ok := f.dates["created-after"]; ok && r.CreatedAt.Before(ts) {
+		return "created-after"
+	}
+	if ts, ok := f.dates["created-before"]; ok && !r.CreatedAt.Before(ts) {
+		return "created-before"
+	}
+	if f.minSize > 0 && r.Size < f.minSize {
+		return "min-size"
+	}
+	if f.maxSize > 0 && r.Size > f.maxSize {
+		return "max-size"
+	}
+	if f.nameMatcher != nil && !f.nameMatcher.MatchString(r.Name) {
+		return "name-regex"
+	}
+	if f.defaultBranch != "" && f.defaultBranch != r.DefaultBranch {
+		return "default-branch"
+	}
+	return ""
+}
+
+func containsFold(vals []string, val string) bool {
+	for _, v := range vals {
+		if strings.EqualFold(v, val) {
+			return true
+		}
+	}
+	return false
+}
@@ -13,12 +13,15 @@ func listReposCmd() *cobra.Command {
 	cmd := cobra.Command{
 		Use: "repos",
 	}
-	cmd.AddCommand(&cobra.Command{
+	list := cobra.Command{
 		Use: "list",
 		Run: func(cmd *cobra.Command, args []string) {
 			listRepos()
 		},
-	})
+	}
+	addRepoFilterFlags(&list)
+	cmd.AddCommand(supportSQLite(&list))
+	cmd.AddCommand(archiveReposCmd(), unarchiveReposCmd())
 	return &cmd
 }
 
@@ -62,13 +65,30 @@ type repo struct {
 	OpenIssuesCount int `json:"open_issues_count"`
 	Disabled        bool
 	Language        interface{} // idk what this is going to be
+	Topics          []string
+	Visibility      string
+}
+
+func (r repo) visibility() string {
+	if r.Visibility != "" {
+		return r.Visibility
+	}
+	if r.Private {
+		return "private"
+	}
+	return "public"
 }
 
 type repoPageIter func(r repo) error
 
 func readRepoPages(iter repoPageIter) {
 	reposProcessed := 0
-	queryByPage("/orgs/netlify/repos", func(raw []byte) bool {
+	repoFilters.prepare()
+	path := "/orgs/netlify/repos"
+	if q := repoFilters.query(); q != "" {
+		path += "?" + q
+	}
+	queryByPage(path, func(raw []byte) bool {
 		repos := []repo{}
 		panicOnErr(json.Unmarshal(raw, &repos))
 		for _, r := range repos {
@@ -78,6 +98,13 @@ func readRepoPages(iter repoPageIter) {
 				)
 				continue
 			}
+			if reason := repoFilters.matches(r); reason != "" {
+				log.Debug("skipping filtered repo",
+					zap.String("repo", r.Name),
+					zap.String("filter", reason),
+				)
+				continue
+			}
 			if !strings.HasPrefix(r.Name, "netlify/") {
 				r.Name = "netlify/" + r.Name
 			}
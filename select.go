@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var selectPaths, filterExprs []string
+
+// selection is the projected fields of an object, it keeps the order of the
+// --select paths in both json and csv
+type selection []csvField
+
+func (s selection) Fields() []csvField {
+	return s
+}
+
+func (s selection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range s {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(f.header)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// withSelection wraps the encoder so objects are filtered and projected before
+// they're written. The paths are matched against the json form of the objects.
+func withSelection(next encoder) encoder {
+	if len(selectPaths) == 0 && len(filterExprs) == 0 {
+		return next
+	}
+
+	filters := []objFilter{}
+	for _, expr := range filterExprs {
+		for _, part := range strings.Split(expr, "&&") {
+			filters = append(filters, parseFilter(part))
+		}
+	}
+
+	return func(obj interface{}) error {
+		generic, err := toGeneric(obj)
+		if err != nil {
+			return err
+		}
+		for _, f := range filters {
+			if !f.matches(generic) {
+				return nil
+			}
+		}
+		if len(selectPaths) == 0 {
+			return next(obj)
+		}
+
+		sel := selection{}
+		for _, p := range selectPaths {
+			val, _ := lookupPath(generic, p)
+			sel = append(sel, csvField{p, val})
+		}
+		return next(sel)
+	}
+}
+
+func toGeneric(obj interface{}) (interface{}, error) {
+	bs, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	dec.UseNumber()
+	return out, dec.Decode(&out)
+}
+
+// lookupPath follows a dotted path like owner.login or items.0.name. Matching a
+// name against an array collects that name from each element. Keys are matched
+// case insensitively since most structs are encoded with their go field names.
+func lookupPath(val interface{}, path string) (interface{}, bool) {
+	if path == "" || path == "." {
+		return val, true
+	}
+	parts := strings.SplitN(path, ".", 2)
+	head, rest := parts[0], ""
+	if len(parts) == 2 {
+		rest = parts[1]
+	}
+
+	switch v := val.(type) {
+	case map[string]interface{}:
+		child, ok := v[head]
+		if !ok {
+			for k, c := range v {
+				if strings.EqualFold(k, head) {
+					child, ok = c, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(child, rest)
+	case []interface{}:
+		if idx, err := strconv.Atoi(head); err == nil {
+			if idx < 0 || idx >= len(v) {
+				return nil, false
+			}
+			return lookupPath(v[idx], rest)
+		}
+		out := []interface{}{}
+		for _, elem := range v {
+			if child, ok := lookupPath(elem, path); ok {
+				out = append(out, child)
+			}
+		}
+		return out, len(out) > 0
+	}
+	return nil, false
+}
+
+var filterOps = []string{"==", "!=", ">=", "<=", "~=", ">", "<"}
+
+// objFilter is a single comparison like archived==false, name~=^api or size>100.
+// A bare path checks that the value is truthy and !path that it isn't.
+type objFilter struct {
+	path  string
+	op    string
+	value string
+	regex *regexp.Regexp
+}
+
+// parseFilter splits on the first operator in the expression, so values like
+// regexes can have operators in them. The longer operator wins where >= and > both match.
+func parseFilter(expr string) objFilter {
+	expr = strings.TrimSpace(expr)
+	idx, op := -1, ""
+	for _, o := range filterOps {
+		if i := strings.Index(expr, o); i > 0 && (idx == -1 || i < idx || (i == idx && len(o) > len(op))) {
+			idx, op = i, o
+		}
+	}
+	if idx > 0 {
+		f := objFilter{
+			path:  strings.TrimSpace(expr[:idx]),
+			op:    op,
+			value: strings.Trim(strings.TrimSpace(expr[idx+len(op):]), `"'`),
+		}
+		if op == "~=" {
+			f.regex = regexp.MustCompile(f.value)
+		}
+		return f
+	}
+	if strings.HasPrefix(expr, "!") {
+		return objFilter{path: strings.TrimPrefix(expr, "!"), op: "!"}
+	}
+	if expr == "" {
+		panic("empty filter expression")
+	}
+	return objFilter{path: expr}
+}
+
+func (f objFilter) matches(obj interface{}) bool {
+	val, ok := lookupPath(obj, f.path)
+	switch f.op {
+	case "":
+		return ok && truthy(val)
+	case "!":
+		return !ok || !truthy(val)
+	}
+	if !ok {
+		return f.op == "!="
+	}
+
+	str := filterString(val)
+	switch f.op {
+	case "==":
+		return str == f.value
+	case "!=":
+		return str != f.value
+	case "~=":
+		return f.regex.MatchString(str)
+	}
+
+	cmp, comparable := compareValues(str, f.value)
+	if !comparable {
+		return false
+	}
+	switch f.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	panic(fmt.Sprintf("unexpected filter op: %s", f.op))
+}
+
+func filterString(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		strs := []string{}
+		for _, e := range v {
+			strs = append(strs, filterString(e))
+		}
+		return strings.Join(strs, ",")
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+// compareValues compares as numbers, then as dates, then as strings
+func compareValues(a, b string) (int, bool) {
+	if af, err := strconv.ParseFloat(a, 64); err == nil {
+		bf, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+	if at, ok := parseFilterTime(a); ok {
+		bt, ok := parseFilterTime(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case at.Before(bt):
+			return -1, true
+		case at.After(bt):
+			return 1, true
+		}
+		return 0, true
+	}
+	return strings.Compare(a, b), true
+}
+
+func parseFilterTime(val string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, filterDateFormat} {
+		if ts, err := time.Parse(layout, val); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func truthy(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case json.Number:
+		f, _ := v.Float64()
+		return f != 0
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return len(v) > 0
+	}
+	return true
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr  string
+		path  string
+		op    string
+		value string
+	}{
+		{"archived==false", "archived", "==", "false"},
+		{" name != 'api' ", "name", "!=", "api"},
+		{"size>=100", "size", ">=", "100"},
+		{"size<=100", "size", "<=", "100"},
+		{"size>100", "size", ">", "100"},
+		{"size<100", "size", "<", "100"},
+		{`name~="^api-"`, "name", "~=", "^api-"},
+		{"name~=a==b", "name", "~=", "a==b"},
+		{"name==a>b", "name", "==", "a>b"},
+		{"archived", "archived", "", ""},
+		{"!archived", "archived", "!", ""},
+	}
+	for _, tc := range tests {
+		f := parseFilter(tc.expr)
+		if f.path != tc.path || f.op != tc.op || f.value != tc.value {
+			t.Errorf("parseFilter(%q) = %q %q %q, expected %q %q %q", tc.expr, f.path, f.op, f.value, tc.path, tc.op, tc.value)
+		}
+		if (f.op == "~=") != (f.regex != nil) {
+			t.Errorf("parseFilter(%q) should only compile a regex for ~=", tc.expr)
+		}
+	}
+}
+
+func testObject(t *testing.T) interface{} {
+	obj, err := toGeneric(map[string]interface{}{
+		"Name":      "api-server",
+		"archived":  false,
+		"size":      120,
+		"topics":    []string{"go", "cli"},
+		"pushed_at": "2021-06-15T10:00:00Z",
+		"owner":     map[string]interface{}{"login": "netlify"},
+		"items": []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b"},
+			map[string]interface{}{"other": "c"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestFilterMatches(t *testing.T) {
+	obj := testObject(t)
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{"archived==false", true},
+		{"archived==true", false},
+		{"archived!=true", true},
+		{"name==api-server", true},
+		{"owner.login==netlify", true},
+		{"name~=^api", true},
+		{"name~=^web", false},
+		{"size>100", true},
+		{"size>120", false},
+		{"size>=120", true},
+		{"size<1000", true},
+		{"size<=119", false},
+		// numbers compare as numbers, not strings
+		{"size>99", true},
+		{"pushed_at>2021-06-01", true},
+		{"pushed_at<2021-06-01", false},
+		{"pushed_at>=2021-06-15T10:00:00Z", true},
+		{"topics==go,cli", true},
+		{"topics", true},
+		{"archived", false},
+		{"!archived", true},
+		{"missing", false},
+		{"!missing", true},
+		{"missing==x", false},
+		{"missing!=x", true},
+		// a number can't be compared with something that isn't
+		{"size>abc", false},
+	}
+	for _, tc := range tests {
+		if actual := parseFilter(tc.expr).matches(obj); actual != tc.expected {
+			t.Errorf("%q matched %v, expected %v", tc.expr, actual, tc.expected)
+		}
+	}
+}
+
+func TestLookupPath(t *testing.T) {
+	obj := testObject(t)
+	tests := []struct {
+		path     string
+		expected interface{}
+		found    bool
+	}{
+		{"name", "api-server", true},
+		{"NAME", "api-server", true},
+		{"owner.login", "netlify", true},
+		{"items.name", []interface{}{"a", "b"}, true},
+		{"items.1.name", "b", true},
+		{"items.5.name", nil, false},
+		{"items.-1", nil, false},
+		{"items.missing", []interface{}{}, false},
+		{"owner.missing", nil, false},
+		{"name.nested", nil, false},
+	}
+	for _, tc := range tests {
+		actual, found := lookupPath(obj, tc.path)
+		if found != tc.found || !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("lookupPath(%q) = %v %v, expected %v %v", tc.path, actual, found, tc.expected, tc.found)
+		}
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a, b       string
+		cmp        int
+		comparable bool
+	}{
+		{"10", "9", 1, true},
+		{"1.5", "1.50", 0, true},
+		{"-2", "1", -1, true},
+		{"10", "abc", 0, false},
+		{"2021-01-02", "2021-01-10", -1, true},
+		{"2021-01-10T00:00:00Z", "2021-01-02", 1, true},
+		{"2021-01-02", "not a date", 0, false},
+		{"abc", "abd", -1, true},
+		{"b", "a", 1, true},
+	}
+	for _, tc := range tests {
+		cmp, comparable := compareValues(tc.a, tc.b)
+		if cmp != tc.cmp || comparable != tc.comparable {
+			t.Errorf("compareValues(%q, %q) = %d %v, expected %d %v", tc.a, tc.b, cmp, comparable, tc.cmp, tc.comparable)
+		}
+	}
+}
+
+func TestWithSelection(t *testing.T) {
+	defer func(paths, exprs []string) {
+		selectPaths, filterExprs = paths, exprs
+	}(selectPaths, filterExprs)
+
+	repos := []repo{
+		{Name: "a", Archived: false, Size: 200},
+		{Name: "b", Archived: true, Size: 200},
+		{Name: "c", Archived: false, Size: 5},
+	}
+	tests := []struct {
+		filters  []string
+		selects  []string
+		expected []string
+	}{
+		{nil, nil, []string{"a", "b", "c"}},
+		// each expression and each && part has to match
+		{[]string{"archived==false && size>10"}, nil, []string{"a"}},
+		{[]string{"archived==false", "size>10"}, nil, []string{"a"}},
+		{[]string{"!archived"}, []string{"name"}, []string{"a", "c"}},
+	}
+	for _, tc := range tests {
+		selectPaths, filterExprs = tc.selects, tc.filters
+		names := []string{}
+		enc := withSelection(func(obj interface{}) error {
+			switch v := obj.(type) {
+			case repo:
+				names = append(names, v.Name)
+			case selection:
+				names = append(names, v[0].value.(string))
+			default:
+				t.Fatalf("unexpected object: %T", obj)
+			}
+			return nil
+		})
+		for _, r := range repos {
+			if err := enc(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(names, tc.expected) {
+			t.Errorf("filters %q kept %v, expected %v", tc.filters, names, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+var sqlitePath string
+
+const sqliteAnnotation = "sqlite"
+
+// supportSQLite marks the commands that only write records --sqlite has tables for
+func supportSQLite(cmd *cobra.Command) *cobra.Command {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[sqliteAnnotation] = "true"
+	return cmd
+}
+
+// sqlTable is a table records are written to, rows are upserted on the key within a run
+// so each run keeps its own history
+type sqlTable struct {
+	name    string
+	columns []string
+	key     []string
+}
+
+var sqlTables = []sqlTable{
+	{
+		name: "repos",
+		columns: []string{
+			"name TEXT NOT NULL",
+			"private BOOLEAN",
+			"archived BOOLEAN",
+			"disabled BOOLEAN",
+			"fork BOOLEAN",
+			"visibility TEXT",
+			"default_branch TEXT",
+			"language TEXT",
+			"size INTEGER",
+			"open_issues INTEGER",
+			"topics TEXT",
+			"url TEXT",
+			"created_at DATETIME",
+			"pushed_at DATETIME",
+		},
+		key: []string{"name"},
+	},
+	{
+		name: "repo_status",
+		columns: []string{
+			"name TEXT NOT NULL",
+			"private BOOLEAN",
+			"archived BOOLEAN",
+			"default_branch TEXT",
+			"pushed_at DATETIME",
+			"code_owners TEXT",
+			"jenkinsfile BOOLEAN",
+			"circle_ci BOOLEAN",
+			"fossa BOOLEAN",
+			"renovate BOOLEAN",
+			"stalebot BOOLEAN",
+			"netlify_toml BOOLEAN",
+			"security BOOLEAN",
+			"actions TEXT",
+		},
+		key: []string{"name"},
+	},
+	{
+		name: "go_mod_refs",
+		columns: []string{
+			"name TEXT NOT NULL",
+			"version TEXT",
+			"private BOOLEAN",
+		},
+		key: []string{"name"},
+	},
+	{
+		name: "projects",
+		columns: []string{
+			"id INTEGER NOT NULL",
+			"name TEXT",
+			"state TEXT",
+			"repo TEXT",
+			"url TEXT",
+		},
+		key: []string{"id"},
+	},
+	{
+		name: "cards",
+		columns: []string{
+			"id INTEGER NOT NULL",
+			"project_id INTEGER",
+			"column_name TEXT",
+			"note TEXT",
+			"content_url TEXT",
+			"archived BOOLEAN",
+			"created_at DATETIME",
+			"updated_at DATETIME",
+		},
+		key: []string{"id"},
+	},
+}
+
+// sqlRow is the values for a table in the order of its columns
+type sqlRow struct {
+	table  string
+	values []interface{}
+}
+
+type sqliteWritable interface {
+	sqlRows() []sqlRow
+}
+
+func (r repo) sqlRows() []sqlRow {
+	return []sqlRow{{"repos", []interface{}{
+		r.Name,
+		r.Private,
+		r.Archived,
+		r.Disabled,
+		r.Fork,
+		r.Visibility,
+		r.DefaultBranch,
+		csvValue(r.Language),
+		r.Size,
+		r.OpenIssuesCount,
+		strings.Join(r.Topics, ","),
+		r.HTMLURL,
+		sqlTime(r.CreatedAt),
+		sqlTime(r.PushedAt),
+	}}}
+}
+
+func (s repoStatus) sqlRows() []sqlRow {
+	return []sqlRow{{"repo_status", []interface{}{
+		s.Name,
+		s.Private,
+		s.Archived,
+		s.DefaultBranch,
+		sqlTime(s.PushedAt),
+		strings.Join(s.CodeOwners, ","),
+		s.Jenkinsfile,
+		s.CircleCI,
+		s.Fossa,
+		s.Renovate,
+		s.Stalebot,
+		s.RootTOML,
+		s.Security,
+		strings.Join(s.Actions, ","),
+	}}}
+}
+
+func (r goModRef) sqlRows() []sqlRow {
+	return []sqlRow{{"go_mod_refs", []interface{}{
+		r.Repo,
+		r.Version,
+		r.Private,
+	}}}
+}
+
+func (p project) sqlRows() []sqlRow {
+	rows := []sqlRow{{"projects", []interface{}{
+		p.ID,
+		p.Name,
+		p.State,
+		p.Repo,
+		p.HTMLURL,
+	}}}
+	for _, col := range p.Cols {
+		for _, card := range col.Cards {
+			rows = append(rows, sqlRow{"cards", []interface{}{
+				card.ID,
+				p.ID,
+				col.Name,
+				card.Note,
+				card.ContentURL,
+				card.Archived,
+				sqlTime(card.CreatedAt),
+				sqlTime(card.UpdatedAt),
+			}})
+		}
+	}
+	return rows
+}
+
+// sqlTime is the text form sqlite's date functions understand, empty times are null
+func sqlTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// buildSQLiteEncoder writes the records to their tables in the database, making
+// the tables if they're missing. Every run gets an ID in the runs table and each
+// row has the run and the time it was written.
+func buildSQLiteEncoder(path string) (encoder, finisher) {
+	db, err := sql.Open("sqlite", path)
+	panicOnErr(err)
+	panicOnErr(createSQLTables(db))
+
+	res, err := db.Exec("INSERT INTO runs (started_at, command) VALUES (?, ?)", sqlTime(time.Now()), strings.Join(os.Args[1:], " "))
+	panicOnErr(err)
+	runID, err := res.LastInsertId()
+	panicOnErr(err)
+	log.Debug("started the sqlite run", zap.String("path", path), zap.Int64("run_id", runID))
+
+	stmts := map[string]*sql.Stmt{}
+	for _, t := range sqlTables {
+		stmt, err := db.Prepare(upsertSQL(t))
+		panicOnErr(err)
+		stmts[t.name] = stmt
+	}
+
+	var count int
+	write := func(obj interface{}) error {
+		rec, ok := obj.(sqliteWritable)
+		if !ok {
+			return fmt.Errorf("%T can't be written to sqlite", obj)
+		}
+		now := sqlTime(time.Now())
+		for _, row := range rec.sqlRows() {
+			args := append([]interface{}{runID, now}, row.values...)
+			if _, err := stmts[row.table].Exec(args...); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}
+	finish := func() error {
+		log.Info("wrote the results to sqlite", zap.String("path", path), zap.Int64("run_id", runID), zap.Int("rows", count))
+		for _, stmt := range stmts {
+			if err := stmt.Close(); err != nil {
+				return err
+			}
+		}
+		return db.Close()
+	}
+	return write, finish
+}
+
+func createSQLTables(db *sql.DB) error {
+	stmts := []string{
+		"PRAGMA journal_mode=WAL",
+		"CREATE TABLE IF NOT EXISTS runs (id INTEGER PRIMARY KEY AUTOINCREMENT, started_at DATETIME NOT NULL, command TEXT)",
+	}
+	for _, t := range sqlTables {
+		cols := append([]string{"run_id INTEGER NOT NULL REFERENCES runs(id)", "recorded_at DATETIME NOT NULL"}, t.columns...)
+		key := append([]string{"run_id"}, t.key...)
+		stmts = append(stmts,
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s, PRIMARY KEY (%s))", t.name, strings.Join(cols, ", "), strings.Join(key, ", ")),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_by_%s ON %s (%s)", t.name, strings.Join(t.key, "_"), t.name, strings.Join(t.key, ", ")),
+		)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to run %q: %w", stmt, err)
+		}
+	}
+	return nil
+}
+
+// upsertSQL inserts a row, or replaces the values if it was already written in this run
+func upsertSQL(t sqlTable) string {
+	names := []string{"run_id", "recorded_at"}
+	for _, c := range t.columns {
+		names = append(names, strings.Fields(c)[0])
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ")
+
+	updates := []string{}
+	for _, n := range names[1:] {
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", n, n))
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+		t.name,
+		strings.Join(names, ", "),
+		placeholders,
+		strings.Join(append([]string{"run_id"}, t.key...), ", "),
+		strings.Join(updates, ", "),
+	)
+}
@@ -4,43 +4,354 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 )
 
 func transferRepoCmd() *cobra.Command {
 	var teamIDs []int
+	var manifest, newName, teamMap string
+	var wait bool
+	var opts transferOpts
 	cmd := cobra.Command{
-		Use: "transfer-repo <repo> <org>",
+		Use: "transfer-repo [<repo> <org>]",
 		Run: func(cmd *cobra.Command, args []string) {
-			transferRepo(args[0], args[1], teamIDs)
+			var reqs []transferRequest
+			if len(args) == 2 {
+				reqs = append(reqs, transferRequest{
+					Repo:    args[0],
+					DestOrg: args[1],
+					TeamIDs: teamIDs,
+					NewName: newName,
+				})
+			}
+			if manifest != "" {
+				reqs = append(reqs, loadTransferManifest(manifest)...)
+			}
+			if len(reqs) == 0 {
+				panic("must provide a repo and org or a manifest")
+			}
+			if teamMap != "" {
+				opts.mapTeams = true
+				opts.teamMap = loadMapping(teamMap)
+			}
+			if !wait && !opts.mapTeams {
+				opts.timeout = 0
+			}
+			transferRepos(reqs, opts)
+		},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 && len(args) != 2 {
+				return fmt.Errorf("accepts 0 or 2 arg(s), received %d", len(args))
+			}
+			return nil
 		},
-		Args: cobra.ExactArgs(2),
 	}
 	cmd.Flags().IntSliceVar(&teamIDs, "team", teamIDs, "a team ID to associate")
+	cmd.Flags().StringVar(&newName, "new-name", "", "an optional new name for the repo in the destination org")
+	cmd.Flags().StringVar(&manifest, "manifest", "", "a csv or yaml file of repos to transfer")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "only run the pre-flight checks")
+	cmd.Flags().BoolVar(&opts.strict, "strict", false, "don't transfer anything if any repo fails the pre-flight checks")
+	cmd.Flags().BoolVar(&wait, "wait", false, "wait for the transfer to complete and verify the team grants")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", 5*time.Minute, "how long to wait for each transfer to complete")
+	cmd.Flags().BoolVar(&opts.mapTeams, "map-teams", false, "re-apply the source repo's team permissions to the teams with the same slug in the destination org, implies --wait")
+	cmd.Flags().StringVar(&teamMap, "team-map", "", "a csv or yaml file mapping source team slugs to destination team slugs, implies --map-teams")
+	cmd.Flags().BoolVar(&opts.createTeams, "create-teams", false, "create any mapped teams that are missing in the destination org")
 
 	return &cmd
 }
 
-func transferRepo(repoName, destOrg string, teams []int) {
-	if !strings.HasPrefix(repoName, "netlify") {
-		repoName = "netlify/" + repoName
+type transferRequest struct {
+	Repo    string `yaml:"repo"`
+	DestOrg string `yaml:"org"`
+	TeamIDs []int  `yaml:"teams"`
+	NewName string `yaml:"new_name"`
+
+	grants []teamGrant
+}
+
+type transferOpts struct {
+	dryRun      bool
+	strict      bool
+	timeout     time.Duration
+	mapTeams    bool
+	createTeams bool
+	teamMap     map[string]string
+}
+
+// destName is the name the repo will have once it is in the destination org
+func (t transferRequest) destName() string {
+	if t.NewName != "" {
+		return t.NewName
+	}
+	parts := strings.SplitAfterN(t.Repo, "/", 2)
+	return parts[len(parts)-1]
+}
+
+const (
+	transferStatusReady       = "ready"
+	transferStatusTransferred = "transferred"
+	transferStatusCompleted   = "completed"
+	transferStatusTimedOut    = "timed_out"
+	transferStatusSkipped     = "skipped"
+	transferStatusFailed      = "failed"
+)
+
+type transferResult struct {
+	Repo    string
+	DestOrg string `json:"dest_org"`
+	NewName string `json:"new_name,omitempty"`
+	Status  string
+	Reason  string `json:",omitempty"`
+	URL     string `json:",omitempty"`
+
+	Owner        string `json:",omitempty"`
+	MissingTeams []int  `json:"missing_teams,omitempty"`
+
+	Teams []string `json:",omitempty"`
+}
+
+func (r transferResult) Fields() []csvField {
+	return []csvField{
+		{"repo", r.Repo},
+		{"dest org", r.DestOrg},
+		{"new name", r.NewName},
+		{"status", r.Status},
+		{"reason", r.Reason},
+		{"url", r.URL},
+		{"owner", r.Owner},
+		{"missing teams", joinInts(r.MissingTeams)},
+		{"teams", strings.Join(r.Teams, ",")},
+	}
+}
+
+func transferRepos(reqs []transferRequest, opts transferOpts) {
+	for i := range reqs {
+		reqs[i].Repo = qualifyRepo(reqs[i].Repo)
+	}
+
+	failures := map[int]string{}
+	seen := map[string]bool{}
+	for i, req := range reqs {
+		dest := req.DestOrg + "/" + req.destName()
+		if seen[dest] {
+			failures[i] = fmt.Sprintf("%s is the destination of multiple rows", dest)
+			continue
+		}
+		seen[dest] = true
+
+		if reason := preflightTransfer(req); reason != "" {
+			failures[i] = reason
+			continue
+		}
+		if opts.mapTeams {
+			reqs[i].grants = resolveTeamGrants(req, opts.teamMap)
+			if missing := missingTeams(reqs[i].grants); len(missing) > 0 && !opts.createTeams {
+				failures[i] = fmt.Sprintf("destination org is missing teams: %s", strings.Join(missing, ","))
+			}
+		}
+	}
+	log.Info("finished pre-flight checks",
+		zap.Int("total", len(reqs)),
+		zap.Int("failed", len(failures)),
+	)
+
+	abort := opts.strict && len(failures) > 0
+	for i, req := range reqs {
+		res := transferResult{
+			Repo:    req.Repo,
+			DestOrg: req.DestOrg,
+			NewName: req.NewName,
+		}
+		switch {
+		case failures[i] != "":
+			res.Status = transferStatusFailed
+			res.Reason = failures[i]
+		case abort:
+			res.Status = transferStatusSkipped
+			res.Reason = "other repos failed the pre-flight checks"
+		case opts.dryRun:
+			res.Status = transferStatusReady
+		default:
+			createMissingTeams(req.DestOrg, req.grants)
+			res = transferRepo(req)
+			if res.Status == transferStatusTransferred && opts.timeout > 0 {
+				waitForTransfer(req, &res, opts.timeout)
+			}
+			if res.Status == transferStatusCompleted && len(req.grants) > 0 {
+				applyTeamGrants(req, &res)
+			}
+		}
+		panicOnErr(enc(res))
+	}
+}
+
+// preflightTransfer returns the reason the transfer can't happen, or "" if it looks good
+func preflightTransfer(req transferRequest) string {
+	code, raw := queryGitHub(fmt.Sprintf("/repos/%s", req.Repo))
+	if code == http.StatusNotFound {
+		return "source repo doesn't exist"
+	}
+	requireCode(http.StatusOK, code, raw)
+
+	src := struct {
+		Archived    bool
+		Permissions struct {
+			Admin bool
+		}
+	}{}
+	panicOnErr(json.Unmarshal(raw, &src))
+	if src.Archived {
+		return "source repo is archived"
+	}
+	if !src.Permissions.Admin {
+		return "token doesn't have admin on the source repo"
+	}
+
+	if code, _ := queryGitHub(fmt.Sprintf("/repos/%s/%s", req.DestOrg, req.destName())); code != http.StatusNotFound {
+		return fmt.Sprintf("%s/%s already exists", req.DestOrg, req.destName())
+	}
+
+	if !isOrgAdmin(req.DestOrg) {
+		return "token doesn't have admin on the destination org"
+	}
+	return ""
+}
+
+var orgAdmin = map[string]bool{}
+
+func isOrgAdmin(org string) bool {
+	if isAdmin, ok := orgAdmin[org]; ok {
+		return isAdmin
 	}
+
+	code, raw := queryGitHub(fmt.Sprintf("/user/memberships/orgs/%s", org))
+	membership := struct {
+		State string
+		Role  string
+	}{}
+	if code == http.StatusOK {
+		panicOnErr(json.Unmarshal(raw, &membership))
+	}
+	orgAdmin[org] = membership.State == "active" && membership.Role == "admin"
+	return orgAdmin[org]
+}
+
+func transferRepo(req transferRequest) transferResult {
 	body := struct {
 		NewOwner string `json:"new_owner"`
+		NewName  string `json:"new_name,omitempty"`
 		TeamIDs  []int  `json:"team_ids,omitempty"`
 	}{
-		NewOwner: destOrg,
-		TeamIDs:  teams,
+		NewOwner: req.DestOrg,
+		NewName:  req.NewName,
+		TeamIDs:  req.TeamIDs,
 	}
 
 	payload, err := json.Marshal(&body)
 	panicOnErr(err)
 
-	code, raw := queryGitHub(fmt.Sprintf("/repos/%s/transfer", repoName), withPayload(payload), withMethod(http.MethodPost))
-	requireCode(code, http.StatusAccepted, raw)
+	res := transferResult{
+		Repo:    req.Repo,
+		DestOrg: req.DestOrg,
+		NewName: req.NewName,
+	}
+	code, raw := queryGitHub(fmt.Sprintf("/repos/%s/transfer", req.Repo), withPayload(payload), withMethod(http.MethodPost))
+	if code != http.StatusAccepted {
+		log.Warn("failed to transfer repo", zap.String("repo", req.Repo), zap.Int("status", code))
+		res.Status = transferStatusFailed
+		res.Reason = fmt.Sprintf("unexpected response code: %d: %s", code, string(raw))
+		return res
+	}
 
-	parts := strings.SplitAfterN(repoName, "/", 2)
-	fmt.Printf("moved repo to https://github.com/%s/%s\n", destOrg, parts[len(parts)-1])
+	res.Status = transferStatusTransferred
+	res.URL = fmt.Sprintf("https://github.com/%s/%s", req.DestOrg, req.destName())
+	return res
+}
+
+// the interval between checks on the state of a transfer
+var transferPollInterval = 5 * time.Second
+
+// waitForTransfer polls the destination until the repo shows up under the new owner,
+// then checks that the requested teams were granted access. GitHub accepts transfers
+// before doing them, so they can still fail after we got the 202.
+func waitForTransfer(req transferRequest, res *transferResult, timeout time.Duration) {
+	dest := fmt.Sprintf("%s/%s", req.DestOrg, req.destName())
+	deadline := time.Now().Add(timeout)
+	for {
+		code, raw := queryGitHub(fmt.Sprintf("/repos/%s", dest))
+		if code == http.StatusOK {
+			var r repo
+			panicOnErr(json.Unmarshal(raw, &r))
+			res.Owner = r.Owner.Login
+			if strings.EqualFold(r.Owner.Login, req.DestOrg) {
+				break
+			}
+		}
+
+		if time.Now().After(deadline) {
+			log.Warn("timed out waiting for the transfer", zap.String("repo", req.Repo), zap.String("dest", dest))
+			res.Status = transferStatusTimedOut
+			res.Reason = fmt.Sprintf("%s didn't show up under %s within %s", req.Repo, req.DestOrg, timeout)
+			return
+		}
+		log.Debug("waiting for the transfer to complete", zap.String("repo", req.Repo), zap.String("dest", dest))
+		time.Sleep(transferPollInterval)
+	}
+
+	res.Status = transferStatusCompleted
+	if len(req.TeamIDs) == 0 {
+		return
+	}
+
+	granted := map[int]bool{}
+	queryByPage(fmt.Sprintf("/repos/%s/teams", dest), func(raw []byte) bool {
+		teams := []struct {
+			ID int
+		}{}
+		panicOnErr(json.Unmarshal(raw, &teams))
+		for _, t := range teams {
+			granted[t.ID] = true
+		}
+		return len(teams) != 0
+	})
+	for _, id := range req.TeamIDs {
+		if !granted[id] {
+			res.MissingTeams = append(res.MissingTeams, id)
+		}
+	}
+	if len(res.MissingTeams) > 0 {
+		res.Status = transferStatusFailed
+		res.Reason = "the repo was transferred but not all teams were granted access"
+	}
+}
+
+// loadTransferManifest reads the rows to transfer from either a yaml list or a
+// csv with the headers: repo, org, teams, new_name. Teams in a csv are separated
+// by spaces or semicolons.
+func loadTransferManifest(file string) []transferRequest {
+	reqs := []transferRequest{}
+	if isYAMLFile(file) {
+		loadYAML(file, &reqs)
+		return reqs
+	}
+
+	for _, row := range loadCSVRecords(file) {
+		req := transferRequest{
+			Repo:    row["repo"],
+			DestOrg: row["org"],
+			NewName: row["new_name"],
+		}
+		for _, t := range strings.FieldsFunc(row["teams"], func(r rune) bool { return r == ';' || r == ' ' }) {
+			id, err := strconv.Atoi(t)
+			panicOnErr(err)
+			req.TeamIDs = append(req.TeamIDs, id)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// teamGrant is a team's access to the source repo and the team it maps to in the destination org
+type teamGrant struct {
+	Slug       string
+	Name       string
+	Permission string
+	DestSlug   string
+	DestID     int
+}
+
+func missingTeams(grants []teamGrant) []string {
+	missing := []string{}
+	for _, g := range grants {
+		if g.DestID == 0 {
+			missing = append(missing, g.DestSlug)
+		}
+	}
+	return missing
+}
+
+// resolveTeamGrants reads the teams with access to the source repo and looks up the
+// matching team in the destination org, either by the same slug or via the mapping.
+func resolveTeamGrants(req transferRequest, mapping map[string]string) []teamGrant {
+	grants := []teamGrant{}
+	queryByPage(fmt.Sprintf("/repos/%s/teams", req.Repo), func(raw []byte) bool {
+		teams := []struct {
+			Slug       string
+			Name       string
+			Permission string
+		}{}
+		panicOnErr(json.Unmarshal(raw, &teams))
+		for _, t := range teams {
+			g := teamGrant{
+				Slug:       t.Slug,
+				Name:       t.Name,
+				Permission: t.Permission,
+				DestSlug:   t.Slug,
+			}
+			if mapped, ok := mapping[t.Slug]; ok {
+				g.DestSlug = mapped
+			}
+			g.DestID = lookupTeamID(req.DestOrg, g.DestSlug)
+			grants = append(grants, g)
+		}
+		return len(teams) != 0
+	})
+
+	log.Debug("resolved team grants",
+		zap.String("repo", req.Repo),
+		zap.Int("teams", len(grants)),
+		zap.Int("missing", len(missingTeams(grants))),
+	)
+	return grants
+}
+
+// lookupTeamID returns the ID of the team in the org, or 0 if it doesn't exist
+func lookupTeamID(org, slug string) int {
+	code, raw := queryGitHub(fmt.Sprintf("/orgs/%s/teams/%s", org, slug))
+	if code == http.StatusNotFound {
+		return 0
+	}
+	requireCode(http.StatusOK, code, raw)
+	team := struct {
+		ID int
+	}{}
+	panicOnErr(json.Unmarshal(raw, &team))
+	return team.ID
+}
+
+func createMissingTeams(org string, grants []teamGrant) {
+	for i, g := range grants {
+		if g.DestID != 0 {
+			continue
+		}
+		// the team might have been created for an earlier repo
+		if id := lookupTeamID(org, g.DestSlug); id != 0 {
+			grants[i].DestID = id
+			continue
+		}
+
+		body, err := json.Marshal(&struct {
+			Name    string `json:"name"`
+			Privacy string `json:"privacy"`
+		}{
+			Name:    g.DestSlug,
+			Privacy: "closed",
+		})
+		panicOnErr(err)
+		code, raw := queryGitHub(fmt.Sprintf("/orgs/%s/teams", org),
+			withMethod(http.MethodPost),
+			withPayload(body),
+		)
+		requireCode(http.StatusCreated, code, raw)
+		team := struct {
+			ID   int
+			Slug string
+		}{}
+		panicOnErr(json.Unmarshal(raw, &team))
+		log.Info("created team", zap.String("org", org), zap.String("slug", team.Slug))
+		grants[i].DestID = team.ID
+		grants[i].DestSlug = team.Slug
+	}
+}
+
+// applyTeamGrants gives each mapped team the same permission it had on the source repo
+func applyTeamGrants(req transferRequest, res *transferResult) {
+	for _, g := range req.grants {
+		body, err := json.Marshal(&struct {
+			Permission string `json:"permission"`
+		}{
+			Permission: g.Permission,
+		})
+		panicOnErr(err)
+
+		code, raw := queryGitHub(fmt.Sprintf("/orgs/%s/teams/%s/repos/%s/%s", req.DestOrg, g.DestSlug, req.DestOrg, req.destName()),
+			withMethod(http.MethodPut),
+			withPayload(body),
+		)
+		if code != http.StatusNoContent {
+			log.Warn("failed to grant team access",
+				zap.String("team", g.DestSlug),
+				zap.String("permission", g.Permission),
+				zap.Int("status", code),
+			)
+			res.Status = transferStatusFailed
+			res.Reason = fmt.Sprintf("failed to grant %s %s access: %d: %s", g.DestSlug, g.Permission, code, string(raw))
+			continue
+		}
+		res.Teams = append(res.Teams, fmt.Sprintf("%s:%s", g.DestSlug, g.Permission))
+	}
+}
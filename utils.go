@@ -1,17 +1,23 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
 )
 
 func queryForFile(repo, path string) bool {
@@ -35,6 +41,7 @@ type fileEntry struct {
 	URL         string `json:"html_url"`
 	RawContent  string `json:"content"`
 	DownloadURL string `json:"download_url"`
+	SHA         string
 	Encoding    string
 	Type        string
 }
@@ -53,10 +60,14 @@ func (e *fileEntry) Contents() []byte {
 	return nil
 }
 
-func queryByPage(path string, cb func(raw []byte) bool) {
+func queryByPage(path string, cb func(raw []byte) bool, opts ...opt) {
 	page := 1
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
 	for {
-		code, raw := queryGitHub(fmt.Sprintf("%s?per_page=100&page=%d", path, page))
+		code, raw := queryGitHub(fmt.Sprintf("%s%sper_page=100&page=%d", path, sep, page), opts...)
 		if code != http.StatusOK {
 			log.Info("Got a !200 response, assuming we got all the pages")
 			return
@@ -92,6 +103,12 @@ func withPayload(data []byte) opt {
 	}
 }
 
+func withHeader(key, value string) opt {
+	return func(r *http.Request) {
+		r.Header.Set(key, value)
+	}
+}
+
 func withMethod(method string) opt {
 	return func(r *http.Request) {
 		r.Method = method
@@ -99,6 +116,12 @@ func withMethod(method string) opt {
 }
 
 func queryGitHub(path string, opts ...opt) (int, []byte) {
+	code, _, raw := queryGitHubResponse(path, opts...)
+	return code, raw
+}
+
+// queryGitHubResponse is queryGitHub that also returns the headers of the response
+func queryGitHubResponse(path string, opts ...opt) (int, http.Header, []byte) {
 	ghQueries++
 
 	prefix := "https://api.github.com"
@@ -151,14 +174,14 @@ func queryGitHub(path string, opts ...opt) (int, []byte) {
 				}
 			}
 			log.Info("Resuming, making that github query now")
-			return queryGitHub(path, opts...)
+			return queryGitHubResponse(path, opts...)
 		}
 	}
 
 	defer rsp.Body.Close()
 	res, err := io.ReadAll(rsp.Body)
 	panicOnErr(err)
-	return rsp.StatusCode, res
+	return rsp.StatusCode, rsp.Header, res
 }
 
 func requireCode(expected, actual int, payload []byte) {
@@ -166,3 +189,88 @@ func requireCode(expected, actual int, payload []byte) {
 		panic(fmt.Sprintf("unexpected response code: %d: %s", actual, string(payload)))
 	}
 }
+
+// qualifyRepo makes sure the repo name includes the owner, defaulting to netlify
+func qualifyRepo(name string) string {
+	if !strings.Contains(name, "/") {
+		return "netlify/" + name
+	}
+	return name
+}
+
+func isYAMLFile(file string) bool {
+	ext := strings.ToLower(filepath.Ext(file))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+func loadYAML(file string, into interface{}) {
+	bs, err := ioutil.ReadFile(file)
+	panicOnErr(err)
+	panicOnErr(yaml.Unmarshal(bs, into))
+}
+
+// loadCSVRecords reads a csv file with a header row into one map per row keyed by
+// the lower cased header
+func loadCSVRecords(file string) []map[string]string {
+	handle, err := os.Open(file)
+	panicOnErr(err)
+	defer handle.Close()
+
+	reader := csv.NewReader(handle)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	panicOnErr(err)
+	if len(rows) == 0 {
+		return nil
+	}
+
+	headers := rows[0]
+	for i, h := range headers {
+		headers[i] = strings.ToLower(strings.TrimSpace(h))
+	}
+
+	records := []map[string]string{}
+	for _, row := range rows[1:] {
+		rec := map[string]string{}
+		for i, v := range row {
+			if i < len(headers) {
+				rec[headers[i]] = strings.TrimSpace(v)
+			}
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func joinInts(vals []int) string {
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ",")
+}
+
+// loadMapping reads a yaml map or a csv with the headers: source, dest
+func loadMapping(file string) map[string]string {
+	mapping := map[string]string{}
+	if isYAMLFile(file) {
+		loadYAML(file, &mapping)
+		return mapping
+	}
+	for _, row := range loadCSVRecords(file) {
+		mapping[row["source"]] = row["dest"]
+	}
+	return mapping
+}
+
+// confirm asks the user on stderr and reads their answer from stdin
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		panic(err)
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}